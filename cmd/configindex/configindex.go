@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/Yelp/paasta-tools-go/pkg/configstore"
+)
+
+const (
+	defaultPaastaDir = "/etc/paasta"
+	defaultSoaDir    = "/nail/etc/services"
+)
+
+type options struct {
+	service  string
+	soaDir   string
+	cacheDir string
+	dirs     []string
+}
+
+func parseFlags(args []string) (*options, error) {
+	opts := &options{}
+	flags := flag.NewFlagSet("configindex", flag.ContinueOnError)
+	flags.StringVar(&opts.service, "service", "", "also index configs of this service")
+	flags.StringVar(&opts.soaDir, "soaDir", defaultSoaDir, "directory with service configs")
+	flags.StringVar(
+		&opts.cacheDir, "cacheDir", "",
+		fmt.Sprintf("write indexes to this cache directory instead of %s files", configstore.IndexFileName),
+	)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s [options] [dir...]\n", os.Args[0])
+		fmt.Fprintf(flags.Output(), "Regenerates config indexes, %s is indexed when no dirs are given\n", defaultPaastaDir)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	opts.dirs = flags.Args()
+	if len(opts.dirs) == 0 {
+		opts.dirs = []string{defaultPaastaDir}
+	}
+	if opts.service != "" {
+		opts.dirs = append(opts.dirs, path.Join(opts.soaDir, opts.service))
+	}
+	return opts, nil
+}
+
+// indexDir rebuilds the index of `dir` and returns where it was written.
+// Stores only read it when `UseIndex` is set.
+func indexDir(dir, cacheDir string) (string, error) {
+	store := configstore.NewStore(dir, nil)
+	idx, err := store.BuildIndex()
+	if err != nil {
+		return "", fmt.Errorf("Failed to index %s: %v", dir, err)
+	}
+	filepath := path.Join(dir, configstore.IndexFileName)
+	if cacheDir != "" {
+		filepath = configstore.IndexCachePath(cacheDir, dir)
+	}
+	if err := configstore.WriteIndex(filepath, idx); err != nil {
+		return "", fmt.Errorf("Failed to write index of %s: %v", dir, err)
+	}
+	return filepath, nil
+}
+
+func main() {
+	opts, err := parseFlags(os.Args[1:])
+	if err != nil {
+		os.Exit(2)
+	}
+
+	failed := false
+	for _, dir := range opts.dirs {
+		filepath, err := indexDir(dir, opts.cacheDir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+			continue
+		}
+		fmt.Printf("indexed %s in %s\n", dir, filepath)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -1,87 +1,319 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
-	"net/http"
-	"net/url"
+	"io"
 	"os"
-	"strings"
+	"time"
+
+	"github.com/Yelp/paasta-tools-go/pkg/hacheck"
+)
+
+// Exit codes, so that scripts can tell why downing a service failed
+const (
+	exitOK        = 0
+	exitUsage     = 2
+	exitTransport = 3
+	exitRejected  = 4
+	exitPartial   = 5
+	exitTimeout   = 6
 )
 
-const DEFAULT_HACHECK_PORT = 6666
-const DEFAULT_HACHECK_HOST = "169.254.255.254"
-
-type HadownOptions struct {
-	ServiceName string
-	ServicePort int
-	ServiceIP   string
-	Reason      string
-	Expiration  float64
-	HacheckHost string
-	HacheckPort int
+// now is overridden in tests
+var now = time.Now
+
+// waitInterval is how often hacheck is polled with --wait
+var waitInterval = time.Second
+
+type hadownOptions struct {
+	hacheck.Options
+	JSON        bool
+	Targets     string
+	Discover    bool
+	NerveConfig string
+	Parallelism int
+	For         time.Duration
+	Until       string
+	Wait        bool
+	WaitTimeout time.Duration
+}
+
+func (opts *hadownOptions) bulk() bool {
+	return opts.Targets != "" || opts.Discover
+}
+
+// hadownResult is printed to stdout when `--json` is set
+type hadownResult struct {
+	Service     string  `json:"service"`
+	ServiceIP   string  `json:"service_ip"`
+	ServicePort int     `json:"service_port"`
+	Reason      string  `json:"reason"`
+	Expiration  float64 `json:"expiration,omitempty"`
+	OK          bool    `json:"ok"`
+	Error       string  `json:"error,omitempty"`
+	HTTPStatus  int     `json:"http_status,omitempty"`
+}
+
+func parseFlags(args []string, opts *hadownOptions) error {
+	flags := flag.NewFlagSet("hadown", flag.ContinueOnError)
+	opts.AddFlags(flags)
+	flags.StringVar(&opts.Reason, "reason", "", "Reason for downing service")
+	flags.Float64Var(&opts.Expiration, "expiration", 0, "Expiration of down status (unix time)")
+	flags.DurationVar(&opts.For, "for", 0, "Expire down status after this duration (e.g. 30m)")
+	flags.StringVar(&opts.Until, "until", "", "Expiration of down status (RFC3339 time)")
+	flags.BoolVar(&opts.Wait, "wait", false, "Wait until the hacheck healthcheck used by nerve fails, so that traffic is drained")
+	flags.StringVar(&opts.CheckURI, "checkURI", "", "Hacheck healthcheck URI polled by --wait, looked up in nerve config by default, the spool state is polled without one")
+	flags.DurationVar(&opts.WaitTimeout, "waitTimeout", time.Minute, "How long to wait with --wait")
+	flags.BoolVar(&opts.JSON, "json", false, "Print result as JSON")
+	flags.StringVar(&opts.Targets, "targets", "", "File with service:port pairs to down, one per line (- for stdin)")
+	flags.BoolVar(&opts.Discover, "discover", false, "Down all services registered in local nerve config")
+	flags.StringVar(&opts.NerveConfig, "nerveConfig", hacheck.DEFAULT_NERVE_CONFIG, "Nerve config used by --discover")
+	flags.IntVar(&opts.Parallelism, "parallelism", 8, "Number of targets to down concurrently")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if opts.bulk() {
+		if opts.ServiceName != "" {
+			return fmt.Errorf("--service can't be combined with --targets or --discover")
+		}
+		if opts.Targets != "" && opts.Discover {
+			return fmt.Errorf("--targets can't be combined with --discover")
+		}
+	} else if err := opts.Validate(); err != nil {
+		return err
+	}
+	if opts.Reason == "" {
+		return fmt.Errorf("Reason is required")
+	}
+	return parseExpiration(opts)
 }
 
-func hadownRequest(opts *HadownOptions) (*http.Request, error) {
-	req_url := fmt.Sprintf("http://%s:%d/spool/%s/%d/", opts.HacheckHost, opts.HacheckPort, opts.ServiceName, opts.ServicePort)
+// parseExpiration sets `Expiration` from `--for` or `--until`, only one of
+// the expiration flags can be used
+func parseExpiration(opts *hadownOptions) error {
+	set := 0
+	for _, isSet := range []bool{opts.Expiration > 0, opts.For != 0, opts.Until != ""} {
+		if isSet {
+			set++
+		}
+	}
+	if set > 1 {
+		return fmt.Errorf("only one of --expiration, --for and --until can be used")
+	}
+
+	if opts.For < 0 {
+		return fmt.Errorf("--for must be positive, got %s", opts.For)
+	} else if opts.For > 0 {
+		opts.Expiration = float64(now().Add(opts.For).Unix())
+	}
+	if opts.Until != "" {
+		until, err := time.Parse(time.RFC3339, opts.Until)
+		if err != nil {
+			return fmt.Errorf("parsing --until: %v", err)
+		}
+		if !until.After(now()) {
+			return fmt.Errorf("--until must be in the future, got %s", opts.Until)
+		}
+		opts.Expiration = float64(until.Unix())
+	}
+	return nil
+}
 
-	data := url.Values{}
-	data.Set("status", "down")
-	data.Set("reason", opts.Reason)
-	if opts.Expiration > 0 {
-		data.Set("expiration", fmt.Sprintf("%.f", opts.Expiration))
+func loadTargets(opts *hadownOptions, stdin io.Reader) ([]hacheck.Target, error) {
+	if opts.Discover {
+		return hacheck.DiscoverNerveTargets(opts.NerveConfig)
 	}
-	req, err := http.NewRequest("POST", req_url, strings.NewReader(data.Encode()))
+	if opts.Targets == "-" {
+		return hacheck.ParseTargets(stdin)
+	}
+	reader, err := os.Open(opts.Targets)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	if opts.ServiceIP != "" {
-		req.Header.Set("X-Nerve-Check-IP", opts.ServiceIP)
+	defer reader.Close()
+	return hacheck.ParseTargets(reader)
+}
+
+// withCheckURIs fills in healthcheck URIs of targets from the nerve config,
+// targets nerve doesn't know are returned unchanged
+func withCheckURIs(opts *hadownOptions, targets []hacheck.Target) []hacheck.Target {
+	if opts.CheckURI != "" {
+		return targets
+	}
+	registered, err := hacheck.DiscoverNerveTargets(opts.NerveConfig)
+	if err != nil {
+		return targets
+	}
+	res := make([]hacheck.Target, len(targets))
+	for idx, target := range targets {
+		res[idx] = target
+		for _, known := range registered {
+			if target.CheckURI == "" &&
+				known.ServiceName == target.ServiceName &&
+				known.ServicePort == target.ServicePort &&
+				(target.ServiceIP == "" || known.ServiceIP == target.ServiceIP) {
+				res[idx].CheckURI = known.CheckURI
+			}
+		}
 	}
-	return req, nil
+	return res
 }
 
-func parseFlags(opts *HadownOptions) error {
-	flag.StringVar(&opts.ServiceName, "service", "", "Service to down")
-	flag.StringVar(&opts.Reason, "reason", "", "Reason for downing service")
-	flag.IntVar(&opts.ServicePort, "servicePort", 0, "Port to set status for")
-	flag.StringVar(&opts.ServiceIP, "serviceIP", "", "IP to set status for")
-	flag.StringVar(&opts.HacheckHost, "host", DEFAULT_HACHECK_HOST, "Host that hacheck is running on")
-	flag.IntVar(&opts.HacheckPort, "port", DEFAULT_HACHECK_PORT, "Port that hacheck is running on")
-	flag.Float64Var(&opts.Expiration, "expiration", 0, "Expiration of down status (unix time)")
-	flag.Parse()
+// waitForDown waits for healthchecks of targets which were downed
+// successfully to fail and replaces their results with the outcome of waiting
+func waitForDown(client *hacheck.Client, opts *hadownOptions, results []hacheck.TargetResult) {
+	downed := []hacheck.Target{}
+	indexes := []int{}
+	for idx, res := range results {
+		if res.Err == nil {
+			downed = append(downed, res.Target)
+			indexes = append(indexes, idx)
+		}
+	}
+	downed = withCheckURIs(opts, downed)
 
-	if opts.ServiceName == "" {
-		return fmt.Errorf("Service name required")
+	ctx, cancel := context.WithTimeout(context.Background(), opts.WaitTimeout)
+	defer cancel()
+	waited := client.WaitForDownAll(ctx, &opts.Options, downed, opts.Parallelism, waitInterval)
+	for idx, res := range waited {
+		results[indexes[idx]] = res
 	}
-	if opts.Reason == "" {
-		return fmt.Errorf("Reason is required")
+}
+
+// exitCode maps an error returned by hacheck.Client to an exit code
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
 	}
-	return nil
+	var respErr *hacheck.ResponseError
+	if errors.As(err, &respErr) {
+		return exitRejected
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return exitTimeout
+	}
+	return exitTransport
 }
 
-func main() {
-	options := &HadownOptions{}
-	err := parseFlags(options)
+// bulkExitCode returns exitPartial if only some targets failed, otherwise
+// the exit code of the first failure
+func bulkExitCode(results []hacheck.TargetResult) int {
+	failed := 0
+	code := exitOK
+	for _, res := range results {
+		if res.Err != nil {
+			failed++
+			if code == exitOK {
+				code = exitCode(res.Err)
+			}
+		}
+	}
+	if failed > 0 && failed < len(results) {
+		return exitPartial
+	}
+	return code
+}
+
+func newResult(opts *hadownOptions, target hacheck.Target, err error) hadownResult {
+	res := hadownResult{
+		Service:     target.ServiceName,
+		ServiceIP:   target.ServiceIP,
+		ServicePort: target.ServicePort,
+		Reason:      opts.Reason,
+		Expiration:  opts.Expiration,
+		OK:          err == nil,
+	}
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		res.Error = err.Error()
+		var respErr *hacheck.ResponseError
+		if errors.As(err, &respErr) {
+			res.HTTPStatus = respErr.StatusCode
+		}
 	}
+	return res
+}
+
+func printResult(out io.Writer, res hadownResult) {
+	if !res.OK {
+		fmt.Fprintf(out, "Failed to mark %s on %s:%d as DOWN: %s\n", res.Service, res.ServiceIP, res.ServicePort, res.Error)
+		return
+	}
+	fmt.Fprintf(out, "Marked %s on %s:%d as DOWN for reason \"%s\"\n", res.Service, res.ServiceIP, res.ServicePort, res.Reason)
+}
 
-	req, err := hadownRequest(options)
+func report(out io.Writer, opts *hadownOptions, results []hacheck.TargetResult) {
+	printed := make([]hadownResult, len(results))
+	failed := 0
+	for idx, tr := range results {
+		printed[idx] = newResult(opts, tr.Target, tr.Err)
+		if tr.Err != nil {
+			failed++
+		}
+	}
+
+	if opts.JSON {
+		if opts.bulk() {
+			json.NewEncoder(out).Encode(printed)
+		} else {
+			json.NewEncoder(out).Encode(printed[0])
+		}
+		return
+	}
+	for _, res := range printed {
+		printResult(out, res)
+	}
+	if opts.bulk() {
+		fmt.Fprintf(out, "Marked %d of %d targets as DOWN, %d failed\n", len(results)-failed, len(results), failed)
+	}
+}
+
+func run(args []string, client *hacheck.Client, stdin io.Reader, out io.Writer) int {
+	options := &hadownOptions{}
+	err := parseFlags(args, options)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
 	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	if !options.bulk() {
+		target := hacheck.Target{
+			ServiceName: options.ServiceName,
+			ServicePort: options.ServicePort,
+			ServiceIP:   options.ServiceIP,
+		}
+		results := []hacheck.TargetResult{{Target: target, Err: client.Down(&options.Options)}}
+		if options.Wait {
+			waitForDown(client, options, results)
+		}
+		report(out, options, results)
+		return exitCode(results[0].Err)
+	}
+
+	targets, err := loadTargets(options, stdin)
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
-	} else {
-		fmt.Printf("Marked %s on %s:%d as DOWN for reason \"%s\"\n", options.ServiceName, options.ServiceIP, options.ServicePort, options.Reason)
+		fmt.Fprintf(os.Stderr, "Failed to load targets: %s\n", err)
+		return exitUsage
+	}
+	if len(targets) == 0 {
+		fmt.Fprintln(os.Stderr, "No targets to down")
+		return exitUsage
+	}
+	results := client.DownAll(&options.Options, targets, options.Parallelism)
+	if options.Wait {
+		waitForDown(client, options, results)
 	}
-	defer resp.Body.Close()
+	report(out, options, results)
+	return bulkExitCode(results)
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], hacheck.NewClient(), os.Stdin, os.Stdout))
 }
@@ -1,51 +1,242 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"os"
+	"path"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/Yelp/paasta-tools-go/pkg/hacheck"
 )
 
-func TestHadownRequest(t *testing.T) {
-	opts := &HadownOptions{
-		ServiceName: "foo",
-		ServicePort: 12345,
+func hacheckArgs(t *testing.T, handler http.HandlerFunc) []string {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	serverURL, _ := url.Parse(server.URL)
+	return []string{"-host", serverURL.Hostname(), "-port", serverURL.Port()}
+}
+
+func TestRun(t *testing.T) {
+	okArgs := hacheckArgs(t, func(w http.ResponseWriter, r *http.Request) {})
+	rejectArgs := hacheckArgs(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no such service", http.StatusNotFound)
+	})
+
+	// grab a free port and close the listener so that connecting fails
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, closedPort, _ := net.SplitHostPort(listener.Addr().String())
+	listener.Close()
+	closedArgs := []string{"-host", "127.0.0.1", "-port", closedPort}
+
+	target := []string{"-service", "foo", "-servicePort", "12345", "-reason", "testing"}
+	testcases := []struct {
+		name     string
+		args     []string
+		expected int
+	}{
+		{"ok", append(okArgs, target...), exitOK},
+		{"rejected", append(rejectArgs, target...), exitRejected},
+		{"transport", append(closedArgs, target...), exitTransport},
+		{"no reason", append(okArgs, "-service", "foo"), exitUsage},
+		{"bad flag", append(okArgs, "-bogus"), exitUsage},
+	}
+	for _, tc := range testcases {
+		out := &bytes.Buffer{}
+		actual := run(tc.args, hacheck.NewClient(), nil, out)
+		if actual != tc.expected {
+			t.Errorf("%s: incorrect exit code, got: %d, want: %d (output %q)", tc.name, actual, tc.expected, out.String())
+		}
+	}
+}
+
+func TestRunJSON(t *testing.T) {
+	args := hacheckArgs(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "broken", http.StatusInternalServerError)
+	})
+	args = append(args, "-json", "-service", "foo", "-servicePort", "12345", "-serviceIP", "10.10.1.2", "-reason", "testing")
+
+	out := &bytes.Buffer{}
+	code := run(args, hacheck.NewClient(), nil, out)
+	if code != exitRejected {
+		t.Errorf("Incorrect exit code, got: %d, want: %d", code, exitRejected)
+	}
+
+	actual := hadownResult{}
+	if err := json.Unmarshal(out.Bytes(), &actual); err != nil {
+		t.Fatalf("Failed to decode output %q: %s", out.String(), err)
+	}
+	expected := hadownResult{
+		Service:     "foo",
 		ServiceIP:   "10.10.1.2",
+		ServicePort: 12345,
 		Reason:      "testing",
-		HacheckHost: "localhost",
-		HacheckPort: 3333,
-		Expiration:  1565214667,
+		OK:          false,
+		Error:       "hacheck returned 500 Internal Server Error: broken",
+		HTTPStatus:  500,
 	}
-	request, err := hadownRequest(opts)
-	if err != nil {
-		t.Errorf("Error %s", err)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Incorrect output, got: %+v, want: %+v", actual, expected)
+	}
+}
+
+func TestRunBulk(t *testing.T) {
+	downed := make(chan string, 10)
+	args := hacheckArgs(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/spool/bad/2/" {
+			http.Error(w, "nope", http.StatusBadRequest)
+			return
+		}
+		downed <- r.URL.Path
+	})
+	args = append(args, "-json", "-targets", "-", "-reason", "maintenance")
+
+	out := &bytes.Buffer{}
+	stdin := strings.NewReader("good:1\nbad:2\n")
+	code := run(args, hacheck.NewClient(), stdin, out)
+	if code != exitPartial {
+		t.Errorf("Incorrect exit code, got: %d, want: %d", code, exitPartial)
+	}
+	close(downed)
+	if path := <-downed; path != "/spool/good/1/" {
+		t.Errorf("Incorrect downed path: %s", path)
+	}
+
+	actual := []hadownResult{}
+	if err := json.Unmarshal(out.Bytes(), &actual); err != nil {
+		t.Fatalf("Failed to decode output %q: %s", out.String(), err)
+	}
+	expected := []hadownResult{
+		{Service: "good", ServicePort: 1, Reason: "maintenance", OK: true},
+		{
+			Service:     "bad",
+			ServicePort: 2,
+			Reason:      "maintenance",
+			Error:       "hacheck returned 400 Bad Request: nope",
+			HTTPStatus:  400,
+		},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Incorrect output, got: %+v, want: %+v", actual, expected)
 	}
 
-	if request.Method != "POST" {
-		t.Errorf("Incorrect method, got: %s, want: POST", request.Method)
+	if code := run(append(args, "-service", "foo"), hacheck.NewClient(), stdin, out); code != exitUsage {
+		t.Errorf("Expected --service with --targets to fail, got: %d", code)
 	}
+}
+
+func TestParseExpiration(t *testing.T) {
+	fixed := time.Date(2019, 8, 7, 21, 51, 7, 0, time.UTC)
+	now = func() time.Time { return fixed }
+	defer func() { now = time.Now }()
 
-	expectedURL := &url.URL{
-		Scheme: "http",
-		Host:   "localhost:3333",
-		Path:   "/spool/foo/12345/",
+	testcases := []struct {
+		opts     hadownOptions
+		expected float64
+		fails    bool
+	}{
+		{opts: hadownOptions{}, expected: 0},
+		{opts: hadownOptions{Options: hacheck.Options{Expiration: 1565214667}}, expected: 1565214667},
+		{opts: hadownOptions{For: 30 * time.Minute}, expected: float64(fixed.Unix() + 1800)},
+		{opts: hadownOptions{Until: "2019-08-07T22:51:07Z"}, expected: float64(fixed.Unix() + 3600)},
+		{opts: hadownOptions{Until: "2019-08-07T23:51:07+01:00"}, expected: float64(fixed.Unix() + 3600)},
+		{opts: hadownOptions{Until: "2019-08-07T20:51:07Z"}, fails: true},
+		{opts: hadownOptions{Until: "tomorrow"}, fails: true},
+		{opts: hadownOptions{For: -time.Minute}, fails: true},
+		{opts: hadownOptions{For: time.Minute, Until: "2019-08-07T22:51:07Z"}, fails: true},
 	}
-	if !reflect.DeepEqual(request.URL, expectedURL) {
-		t.Errorf("Incorrect URL, got: %v, want: %v", request.URL, expectedURL)
+	for _, tc := range testcases {
+		opts := tc.opts
+		err := parseExpiration(&opts)
+		if tc.fails {
+			if err == nil {
+				t.Errorf("Expected %+v to fail", tc.opts)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error for %+v: %s", tc.opts, err)
+		} else if opts.Expiration != tc.expected {
+			t.Errorf("Incorrect expiration for %+v, got: %f, want: %f", tc.opts, opts.Expiration, tc.expected)
+		}
 	}
+}
+
+func TestRunWait(t *testing.T) {
+	waitInterval = time.Millisecond
+	defer func() { waitInterval = time.Second }()
+
+	checks := 0
+	args := hacheckArgs(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			return
+		}
+		if r.URL.Path != "/http/foo/0/status" {
+			t.Errorf("Unexpected healthcheck %s", r.URL.Path)
+		}
+		checks++
+		if checks >= 3 {
+			http.Error(w, "Service foo in down state", http.StatusServiceUnavailable)
+		}
+	})
+	args = append(args, "-service", "foo", "-reason", "testing", "-wait", "-checkURI", "/http/foo/0/status")
 
-	request.ParseForm()
-	expectedForm := url.Values{
-		"status":     {"down"},
-		"reason":     {"testing"},
-		"expiration": {"1565214667"},
+	out := &bytes.Buffer{}
+	if code := run(args, hacheck.NewClient(), nil, out); code != exitOK {
+		t.Errorf("Incorrect exit code, got: %d, want: %d (output %q)", code, exitOK, out.String())
 	}
-	if !reflect.DeepEqual(request.PostForm, expectedForm) {
-		t.Errorf("Incorrect request body, got: %v, want: %v", request.PostForm, expectedForm)
+	if checks != 3 {
+		t.Errorf("Incorrect number of healthchecks, got: %d, want: 3", checks)
 	}
 
-	ip_header := request.Header.Get("X-Nerve-Check-IP")
-	if ip_header != opts.ServiceIP {
-		t.Errorf("Incorrect IP header value, got: %v, want: %v", ip_header, opts.ServiceIP)
+	// the healthcheck is looked up in the nerve config, which nerve keeps
+	// passing until hacheck stops serving it
+	nerveConfig := path.Join(t.TempDir(), "nerve.conf.json")
+	config := `{"services": {"foo.main.norcal:10.1.2.3.31000.new": {"host": "10.1.2.3", "port": 31000, "checks": [{"uri": "/http/foo.main/31000/status"}]}}}`
+	if err := os.WriteFile(nerveConfig, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	upArgs := hacheckArgs(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" && r.URL.Path != "/http/foo.main/31000/status" {
+			t.Errorf("Unexpected healthcheck %s", r.URL.Path)
+		}
+	})
+	upArgs = append(upArgs, "-service", "foo.main", "-servicePort", "31000", "-reason", "testing",
+		"-wait", "-waitTimeout", "20ms", "-nerveConfig", nerveConfig)
+	if code := run(upArgs, hacheck.NewClient(), nil, out); code != exitTimeout {
+		t.Errorf("Incorrect exit code, got: %d, want: %d (output %q)", code, exitTimeout, out.String())
+	}
+
+	// without a nerve config the spool state is polled instead
+	queries := 0
+	spoolArgs := hacheckArgs(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			return
+		}
+		if r.URL.Path != "/spool/foo/0/" {
+			t.Errorf("Unexpected query %s", r.URL.Path)
+		}
+		queries++
+		fmt.Fprint(w, `{"status": "down", "reason": "testing"}`)
+	})
+	spoolArgs = append(spoolArgs, "-service", "foo", "-reason", "testing", "-wait",
+		"-nerveConfig", path.Join(t.TempDir(), "missing.json"))
+	out.Reset()
+	if code := run(spoolArgs, hacheck.NewClient(), nil, out); code != exitOK {
+		t.Errorf("Incorrect exit code, got: %d, want: %d (output %q)", code, exitOK, out.String())
+	}
+	if queries != 1 || !strings.HasPrefix(out.String(), "Marked foo") {
+		t.Errorf("Unexpected result after %d queries: %q", queries, out.String())
 	}
 }
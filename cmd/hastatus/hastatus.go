@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Yelp/paasta-tools-go/pkg/hacheck"
+)
+
+func parseFlags(opts *hacheck.Options) error {
+	opts.AddFlags(flag.CommandLine)
+	flag.Parse()
+
+	return opts.Validate()
+}
+
+func formatStatus(opts *hacheck.Options, status *hacheck.SpoolStatus) string {
+	res := fmt.Sprintf("%s on %s:%d is %s", opts.ServiceName, opts.ServiceIP, opts.ServicePort, status.Status)
+	if status.Reason != "" {
+		res += fmt.Sprintf(" for reason \"%s\"", status.Reason)
+	}
+	if status.Expiration > 0 {
+		expiration := time.Unix(int64(status.Expiration), 0).UTC()
+		res += fmt.Sprintf(" until %s", expiration.Format(time.RFC3339))
+	}
+	return res
+}
+
+func main() {
+	options := &hacheck.Options{}
+	err := parseFlags(options)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	status, err := hacheck.NewClient().Query(options)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Println(formatStatus(options, status))
+}
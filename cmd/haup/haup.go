@@ -0,0 +1,32 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Yelp/paasta-tools-go/pkg/hacheck"
+)
+
+func parseFlags(opts *hacheck.Options) error {
+	opts.AddFlags(flag.CommandLine)
+	flag.Parse()
+
+	return opts.Validate()
+}
+
+func main() {
+	options := &hacheck.Options{}
+	err := parseFlags(options)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	err = hacheck.NewClient().Up(options)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Printf("Marked %s on %s:%d as UP\n", options.ServiceName, options.ServiceIP, options.ServicePort)
+}
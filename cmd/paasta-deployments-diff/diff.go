@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Yelp/paasta-tools-go/pkg/deployments"
+)
+
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+type options struct {
+	context int
+	json    bool
+	old     string
+	new     string
+}
+
+func parseFlags(args []string, out io.Writer) (*options, error) {
+	opts := &options{}
+	flags := flag.NewFlagSet("paasta-deployments-diff", flag.ContinueOnError)
+	flags.SetOutput(out)
+	flags.Usage = func() {
+		fmt.Fprintln(out, "usage: paasta-deployments-diff [flags] OLD NEW")
+		fmt.Fprintln(out, "compares two deployments.json files, - reads one of them from stdin")
+		flags.PrintDefaults()
+	}
+	flags.IntVar(&opts.context, "context", 3, "lines of context in the YAML diff")
+	flags.BoolVar(&opts.json, "json", false, "print changes as JSON")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return nil, fmt.Errorf("expected OLD and NEW deployments, got %v", flags.Args())
+	}
+	opts.old, opts.new = flags.Arg(0), flags.Arg(1)
+	if opts.old == "-" && opts.new == "-" {
+		return nil, fmt.Errorf("only one of OLD and NEW can be read from stdin")
+	}
+	return opts, nil
+}
+
+func readDeployments(file string, stdin io.Reader) (*deployments.Deployments, error) {
+	var data []byte
+	var err error
+	if file == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return nil, err
+	}
+	d, err := deployments.Unmarshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse %s: %v", file, err)
+	}
+	return d, nil
+}
+
+// run writes changes to `out`, usage and errors go to `errOut` so that they
+// don't corrupt JSON output
+func run(args []string, stdin io.Reader, out, errOut io.Writer) int {
+	opts, err := parseFlags(args, errOut)
+	if err != nil {
+		fmt.Fprintln(errOut, err)
+		return exitUsage
+	}
+
+	old, err := readDeployments(opts.old, stdin)
+	if err != nil {
+		fmt.Fprintln(errOut, err)
+		return exitError
+	}
+	new, err := readDeployments(opts.new, stdin)
+	if err != nil {
+		fmt.Fprintln(errOut, err)
+		return exitError
+	}
+	changes := deployments.Diff(old, new)
+
+	if opts.json {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(changes); err != nil {
+			fmt.Fprintln(errOut, err)
+			return exitError
+		}
+		return exitOK
+	}
+	if changes.Empty() {
+		fmt.Fprintln(out, "No changes")
+		return exitOK
+	}
+	for _, line := range changes.Summary() {
+		fmt.Fprintln(out, line)
+	}
+	diff, err := changes.YamlDiff(opts.context)
+	if err != nil {
+		fmt.Fprintln(errOut, err)
+		return exitError
+	}
+	fmt.Fprintf(out, "\n%s", diff)
+	return exitOK
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+const (
+	oldDeployments = `{"v1": {}, "v2": {"deployments": {"prod.everything": {"docker_image": "services-fluffy:paasta-abc123", "git_sha": "abc123", "image_version": null}}, "controls": {"fluffy:norcal-prod.main": {"desired_state": "start", "force_bounce": null}}}}`
+	newDeployments = `{"v1": {}, "v2": {"deployments": {"prod.everything": {"docker_image": "services-fluffy:paasta-def456", "git_sha": "def456", "image_version": null}}, "controls": {"fluffy:norcal-prod.main": {"desired_state": "stop", "force_bounce": null}}}}`
+)
+
+func TestRun(test *testing.T) {
+	dir := test.TempDir()
+	newFile := path.Join(dir, "deployments.json")
+	if err := os.WriteFile(newFile, []byte(newDeployments), 0644); err != nil {
+		test.Fatal(err)
+	}
+
+	out, errOut := &bytes.Buffer{}, &bytes.Buffer{}
+	code := run([]string{"-", newFile}, strings.NewReader(oldDeployments), out, errOut)
+	expected := "prod.everything: abc123 -> def456\nfluffy:norcal-prod.main: desired_state start -> stop\n\n--- Old\n+++ New\n"
+	if code != exitOK || !strings.HasPrefix(out.String(), expected) {
+		test.Errorf("unexpected result %d: %s", code, out)
+	}
+
+	out.Reset()
+	code = run([]string{"-json", newFile, "-"}, strings.NewReader(oldDeployments), out, errOut)
+	var changes map[string][]map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &changes); err != nil || code != exitOK {
+		test.Fatalf("unexpected result %d: %s", code, out)
+	}
+	if len(changes["deployments"]) != 1 || changes["controls"][0]["type"] != "modified" {
+		test.Errorf("unexpected changes: %s", out)
+	}
+
+	out.Reset()
+	code = run([]string{newFile, newFile}, nil, out, errOut)
+	if code != exitOK || out.String() != "No changes\n" {
+		test.Errorf("unexpected result %d: %s", code, out)
+	}
+
+	out.Reset()
+	code = run([]string{"-json", newFile, path.Join(dir, "missing.json")}, nil, out, errOut)
+	if code != exitError || out.Len() != 0 || !strings.Contains(errOut.String(), "missing.json") {
+		test.Errorf("expected error on stderr, got %d: %s, stderr: %s", code, out, errOut)
+	}
+	errOut.Reset()
+	if code := run([]string{newFile}, nil, out, errOut); code != exitUsage || out.Len() != 0 {
+		test.Errorf("expected usage error, got %d: %s", code, out)
+	}
+	if !strings.HasPrefix(errOut.String(), "usage: paasta-deployments-diff") {
+		test.Errorf("expected usage on stderr, got %s", errOut)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/Yelp/paasta-tools-go/pkg/soaconfigs"
+	"github.com/Yelp/paasta-tools-go/pkg/validation"
+)
+
+const (
+	exitOK      = 0
+	exitInvalid = 1
+	exitUsage   = 2
+)
+
+type options struct {
+	service         string
+	soaDir          string
+	strict          bool
+	skipDeployments bool
+}
+
+func parseFlags(args []string, out io.Writer) (*options, error) {
+	opts := &options{}
+	flags := flag.NewFlagSet("paasta-validate", flag.ContinueOnError)
+	flags.SetOutput(out)
+	flags.StringVar(&opts.service, "service", "", "service to validate, defaults to the current directory")
+	flags.StringVar(&opts.soaDir, "soaDir", soaconfigs.DefaultRoot, "directory with soa-configs of all services")
+	// spellings of paasta-tools `paasta validate`, which this replaces
+	flags.StringVar(&opts.service, "s", "", "alias for -service")
+	flags.StringVar(&opts.soaDir, "y", soaconfigs.DefaultRoot, "alias for -soaDir")
+	flags.StringVar(&opts.soaDir, "yelpsoa-config-root", soaconfigs.DefaultRoot, "alias for -soaDir")
+	flags.BoolVar(&opts.strict, "strict", false, "report keys which are not in the schema")
+	flags.BoolVar(&opts.skipDeployments, "skipDeployments", false, "don't check deploy groups against deployments.json")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %v", flags.Args())
+	}
+
+	if opts.service == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		opts.soaDir, opts.service = filepath.Split(cwd)
+	}
+	return opts, nil
+}
+
+func run(args []string, out io.Writer) int {
+	opts, err := parseFlags(args, out)
+	if err != nil {
+		fmt.Fprintln(out, err)
+		return exitUsage
+	}
+
+	validator := validation.NewValidator(opts.soaDir)
+	validator.Strict = opts.strict
+	validator.SkipDeployments = opts.skipDeployments
+	errors, err := validator.ValidateService(opts.service)
+	if err != nil {
+		fmt.Fprintln(out, err)
+		return exitInvalid
+	}
+	for _, err := range errors {
+		fmt.Fprintln(out, err)
+	}
+	if len(errors) > 0 {
+		fmt.Fprintf(out, "%d problems found in %s\n", len(errors), opts.service)
+		return exitInvalid
+	}
+	fmt.Fprintf(out, "All checks passed for %s\n", opts.service)
+	return exitOK
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout))
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestRun(test *testing.T) {
+	soaDir := test.TempDir()
+	if err := os.Mkdir(path.Join(soaDir, "fluffy"), 0755); err != nil {
+		test.Fatal(err)
+	}
+	config := path.Join(soaDir, "fluffy", "kubernetes-norcal.yaml")
+	if err := os.WriteFile(config, []byte("main:\n  mem: 1024\n"), 0644); err != nil {
+		test.Fatal(err)
+	}
+
+	out := &bytes.Buffer{}
+	code := run([]string{"-soaDir", soaDir, "-service", "fluffy", "-skipDeployments"}, out)
+	if code != exitOK || !strings.Contains(out.String(), "All checks passed") {
+		test.Errorf("unexpected result %d: %s", code, out)
+	}
+
+	if err := os.WriteFile(config, []byte("main:\n  mem: lots\n"), 0644); err != nil {
+		test.Fatal(err)
+	}
+	out.Reset()
+	code = run([]string{"-soaDir", soaDir, "-service", "fluffy", "-skipDeployments"}, out)
+	if code != exitInvalid || !strings.Contains(out.String(), "fluffy/kubernetes-norcal.yaml:2: main.mem:") {
+		test.Errorf("unexpected result %d: %s", code, out)
+	}
+
+	out.Reset()
+	if code := run([]string{"-bogus"}, out); code != exitUsage {
+		test.Errorf("expected usage error, got %d", code)
+	}
+}
+
+func TestParseFlagsPaastaToolsStyle(test *testing.T) {
+	for _, args := range [][]string{
+		{"-s", "fluffy", "-y", "/nail/soa"},
+		{"--service", "fluffy", "--yelpsoa-config-root", "/nail/soa"},
+		{"-service", "fluffy", "-soaDir", "/nail/soa"},
+	} {
+		opts, err := parseFlags(args, &bytes.Buffer{})
+		if err != nil || opts.service != "fluffy" || opts.soaDir != "/nail/soa" {
+			test.Errorf("unexpected options for %v: %+v, err: %v", args, opts, err)
+		}
+	}
+}
@@ -10,7 +10,7 @@ import (
 	"runtime"
 	"strings"
 
-	"github.com/Yelp/paasta-tools-go/pkg/configstore"
+	"github.com/Yelp/paasta-tools-go/pkg/systemconfig"
 	paastaversion "github.com/Yelp/paasta-tools-go/pkg/version"
 	paastazipkin "github.com/Yelp/paasta-tools-go/pkg/zipkin"
 	"github.com/openzipkin/zipkin-go"
@@ -36,11 +36,11 @@ func listPaastaCommands() (map[string]bool, error) {
 func paasta() (int, error) {
 	zipkinURL, _ := os.LookupEnv("PAASTA_ZIPKIN_URL")
 	if zipkinURL == "" {
-		store := configstore.NewStore(
-			"/etc/paasta",
-			map[string]string{"paasta_zipkin_url": "paasta"},
-		)
-		store.Load("paasta_zipkin_url", &zipkinURL)
+		var err error
+		zipkinURL, err = systemconfig.NewDefault().ZipkinURL()
+		if err != nil {
+			klog.V(10).Infof("Error reading zipkin URL: %s\n", err)
+		}
 	}
 
 	zr, zt, err := paastazipkin.InitZipkin(zipkinURL)
@@ -2,7 +2,7 @@ module github.com/Yelp/paasta-tools-go
 
 require (
 	github.com/dlespiau/kube-test-harness v0.0.0-20200730130322-72c5b0037f4a
-	github.com/golang/glog v1.0.0
+	github.com/golang/glog v1.1.0
 	github.com/mitchellh/mapstructure v1.4.1
 	github.com/openzipkin/zipkin-go v0.2.2
 	github.com/pmezard/go-difflib v1.0.0
@@ -11,6 +11,7 @@ require (
 	github.yelpcorp.com/go-packages/monk v0.0.0-20210923142653-46be63e1f16e
 	golang.org/x/oauth2 v0.12.0
 	gopkg.in/yaml.v2 v2.4.0
+	gopkg.in/yaml.v3 v3.0.1
 	k8s.io/api v0.29.15
 	k8s.io/apimachinery v0.29.15
 	k8s.io/client-go v0.29.15
@@ -48,9 +49,9 @@ require (
 	golang.org/x/text v0.14.0 // indirect
 	golang.org/x/time v0.3.0 // indirect
 	google.golang.org/appengine v1.6.7 // indirect
+	google.golang.org/grpc v1.58.3 // indirect
 	google.golang.org/protobuf v1.33.0 // indirect
 	gopkg.in/inf.v0 v0.9.1 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 	k8s.io/klog/v2 v2.110.1 // indirect
 	k8s.io/kube-openapi v0.0.0-20231010175941-2dd684a91f00 // indirect
 	k8s.io/utils v0.0.0-20230726121419-3b25d923346b // indirect
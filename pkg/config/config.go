@@ -2,10 +2,16 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"io/fs"
 	"io/ioutil"
 	"os"
 	"path"
+	"strings"
+
+	"gopkg.in/yaml.v2"
 )
 
 type ConfigReader interface {
@@ -17,6 +23,7 @@ type ConfigFileReader struct {
 	Filename string
 }
 
+// ParseContent decodes JSON from reader into content
 func ParseContent(reader io.Reader, content interface{}) error {
 	buf, err := ioutil.ReadAll(reader)
 	if err != nil {
@@ -26,15 +33,101 @@ func ParseContent(reader io.Reader, content interface{}) error {
 	return err
 }
 
+// ParseContentForFile decodes YAML for filenames ending with `.yaml` or
+// `.yml`, and JSON otherwise. YAML is converted to JSON before decoding, so
+// that content is decoded by its `json` tags either way.
+func ParseContentForFile(reader io.Reader, filename string, content interface{}) error {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".yaml", ".yml":
+		buf, err := ioutil.ReadAll(reader)
+		if err != nil {
+			return err
+		}
+		var value interface{}
+		if err := yaml.Unmarshal(buf, &value); err != nil {
+			return err
+		}
+		buf, err = json.Marshal(stringKeys(value))
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(buf, content)
+	default:
+		return ParseContent(reader, content)
+	}
+}
+
+// stringKeys converts maps decoded from YAML to `map[string]interface{}`,
+// which can be encoded as JSON
+func stringKeys(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		res := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			res[fmt.Sprint(key)] = stringKeys(val)
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, len(v))
+		for idx, val := range v {
+			res[idx] = stringKeys(val)
+		}
+		return res
+	}
+	return value
+}
+
 func (configReader ConfigFileReader) FileNameForConfig() string {
 	return path.Join(configReader.Basedir, configReader.Filename)
 }
 
 func (configReader ConfigFileReader) Read(content interface{}) error {
-	reader, err := os.Open(configReader.FileNameForConfig())
+	filename := configReader.FileNameForConfig()
+	reader, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
 	defer reader.Close()
+	return ParseContentForFile(reader, filename, content)
+}
+
+// ConfigFSReader reads `Filename` from `FS`, e.g. an `embed.FS`
+type ConfigFSReader struct {
+	FS       fs.FS
+	Filename string
+}
+
+func (configReader ConfigFSReader) Read(content interface{}) error {
+	reader, err := configReader.FS.Open(configReader.Filename)
 	if err != nil {
 		return err
 	}
-	return ParseContent(reader, content)
+	defer reader.Close()
+	return ParseContentForFile(reader, configReader.Filename, content)
+}
+
+// ChainedConfigReader reads config from the first of `Readers` which finds
+// it, readers failing with errors other than `fs.ErrNotExist` stop the chain
+type ChainedConfigReader struct {
+	Readers []ConfigReader
+}
+
+// NewChainedFileReader creates a reader looking for `filename` in `basedirs`
+// in order
+func NewChainedFileReader(filename string, basedirs ...string) ChainedConfigReader {
+	readers := make([]ConfigReader, len(basedirs))
+	for idx, basedir := range basedirs {
+		readers[idx] = ConfigFileReader{Basedir: basedir, Filename: filename}
+	}
+	return ChainedConfigReader{Readers: readers}
+}
+
+func (configReader ChainedConfigReader) Read(content interface{}) error {
+	for _, reader := range configReader.Readers {
+		err := reader.Read(content)
+		if err == nil || !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return fmt.Errorf("config not found in any of %d sources: %w", len(configReader.Readers), fs.ErrNotExist)
 }
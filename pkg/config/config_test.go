@@ -3,9 +3,14 @@ package config
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"io"
+	"io/fs"
+	"os"
+	"path"
 	"reflect"
 	"testing"
+	"testing/fstest"
 )
 
 type FakeConfig struct {
@@ -52,3 +57,85 @@ func TestFileNameForConfig(test *testing.T) {
 		test.Errorf("filename incorrect incorrect, got: %s, want: %s.", actual, expected)
 	}
 }
+
+func writeFile(test *testing.T, filename, content string) {
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		test.Fatal(err)
+	}
+}
+
+func TestConfigFileReaderYAML(test *testing.T) {
+	dir := test.TempDir()
+	writeFile(test, path.Join(dir, "foo.yaml"), "foo: bar\n")
+	writeFile(test, path.Join(dir, "foo.json"), `{"foo": "baz"}`)
+
+	actual := map[string]string{}
+	err := ConfigFileReader{Basedir: dir, Filename: "foo.yaml"}.Read(&actual)
+	if err != nil || actual["foo"] != "bar" {
+		test.Errorf("failed to read yaml, got: %v, err: %v", actual, err)
+	}
+	err = ConfigFileReader{Basedir: dir, Filename: "foo.json"}.Read(&actual)
+	if err != nil || actual["foo"] != "baz" {
+		test.Errorf("failed to read json, got: %v, err: %v", actual, err)
+	}
+	err = ConfigFileReader{Basedir: dir, Filename: "missing.json"}.Read(&actual)
+	if !errors.Is(err, fs.ErrNotExist) {
+		test.Errorf("expected not exist error, got: %v", err)
+	}
+}
+
+func TestConfigFileReaderJSONTags(test *testing.T) {
+	type registryConfig struct {
+		DockerRegistry string         `json:"docker_registry"`
+		Ports          map[string]int `json:"ports"`
+	}
+	dir := test.TempDir()
+	writeFile(test, path.Join(dir, "a.yaml"), "docker_registry: docker.yelp.com\nports:\n  http: 80\n")
+	writeFile(test, path.Join(dir, "a.json"), `{"docker_registry": "docker.yelp.com", "ports": {"http": 80}}`)
+
+	for _, filename := range []string{"a.yaml", "a.json"} {
+		actual := registryConfig{}
+		err := ConfigFileReader{Basedir: dir, Filename: filename}.Read(&actual)
+		if err != nil || actual.DockerRegistry != "docker.yelp.com" || actual.Ports["http"] != 80 {
+			test.Errorf("failed to read %s, got: %+v, err: %v", filename, actual, err)
+		}
+	}
+}
+
+func TestConfigFSReader(test *testing.T) {
+	fsys := fstest.MapFS{
+		"paasta/foo.yml": {Data: []byte("foo: bar\n")},
+	}
+	actual := FakeConfig{}
+	err := ConfigFSReader{FS: fsys, Filename: "paasta/foo.yml"}.Read(&actual)
+	if err != nil || actual.Foo != "bar" {
+		test.Errorf("failed to read from fs, got: %+v, err: %v", actual, err)
+	}
+}
+
+func TestChainedConfigReader(test *testing.T) {
+	override := test.TempDir()
+	base := test.TempDir()
+	writeFile(test, path.Join(base, "foo.json"), `{"foo": "base"}`)
+
+	reader := NewChainedFileReader("foo.json", override, base)
+	actual := FakeConfig{}
+	if err := reader.Read(&actual); err != nil || actual.Foo != "base" {
+		test.Errorf("failed to read base, got: %+v, err: %v", actual, err)
+	}
+
+	writeFile(test, path.Join(override, "foo.json"), `{"foo": "override"}`)
+	if err := reader.Read(&actual); err != nil || actual.Foo != "override" {
+		test.Errorf("failed to read override, got: %+v, err: %v", actual, err)
+	}
+
+	writeFile(test, path.Join(override, "foo.json"), `{"foo": `)
+	if err := reader.Read(&actual); err == nil {
+		test.Errorf("broken override should stop the chain")
+	}
+
+	err := NewChainedFileReader("missing.json", override, base).Read(&actual)
+	if !errors.Is(err, fs.ErrNotExist) {
+		test.Errorf("expected not exist error, got: %v", err)
+	}
+}
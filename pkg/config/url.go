@@ -0,0 +1,80 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// DefaultURLReaderTimeout limits how long `ConfigURLReader` waits for http
+// sources when `Timeout` isn't set
+const DefaultURLReaderTimeout = 10 * time.Second
+
+// ConfigURLReader reads config from a `file://`, `http://` or `https://` URL,
+// format is chosen by the extension of the URL path. Missing files and 404
+// responses are reported as `fs.ErrNotExist`, so that it can be chained.
+type ConfigURLReader struct {
+	URL     string
+	Timeout time.Duration
+	Client  *http.Client
+}
+
+func (configReader ConfigURLReader) Read(content interface{}) error {
+	u, err := url.Parse(configReader.URL)
+	if err != nil {
+		return fmt.Errorf("Failed to parse %s: %v", configReader.URL, err)
+	}
+	switch u.Scheme {
+	case "file":
+		reader, err := os.Open(u.Path)
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+		return ParseContentForFile(reader, u.Path, content)
+	case "http", "https":
+		return configReader.readHTTP(u, content)
+	default:
+		return fmt.Errorf("unsupported config URL scheme: %s", u.Scheme)
+	}
+}
+
+func (configReader ConfigURLReader) readHTTP(u *url.URL, content interface{}) error {
+	timeout := configReader.Timeout
+	if timeout == 0 {
+		timeout = DefaultURLReaderTimeout
+	}
+	client := configReader.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Failed to fetch %s: %v", u.Redacted(), err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		io.Copy(io.Discard, resp.Body)
+		return fmt.Errorf("%s: %w", u.Redacted(), fs.ErrNotExist)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Failed to fetch %s: %s", u.Redacted(), resp.Status)
+	}
+	if err := ParseContentForFile(resp.Body, u.Path, content); err != nil {
+		return fmt.Errorf("Failed to parse %s: %v", u.Redacted(), err)
+	}
+	return nil
+}
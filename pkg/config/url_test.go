@@ -0,0 +1,64 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestConfigURLReader(test *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/paasta/foo.yaml":
+			w.Write([]byte("foo: bar\n"))
+		case "/paasta/slow.json":
+			time.Sleep(200 * time.Millisecond)
+			w.Write([]byte(`{"foo": "slow"}`))
+		case "/paasta/broken.json":
+			http.Error(w, "oops", http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	actual := FakeConfig{}
+	err := ConfigURLReader{URL: server.URL + "/paasta/foo.yaml"}.Read(&actual)
+	if err != nil || actual.Foo != "bar" {
+		test.Errorf("failed to read over http, got: %+v, err: %v", actual, err)
+	}
+
+	err = ConfigURLReader{URL: server.URL + "/paasta/slow.json", Timeout: 20 * time.Millisecond}.Read(&actual)
+	if err == nil {
+		test.Errorf("expected timeout")
+	}
+
+	err = ConfigURLReader{URL: server.URL + "/paasta/broken.json"}.Read(&actual)
+	if err == nil || errors.Is(err, fs.ErrNotExist) {
+		test.Errorf("expected server error, got: %v", err)
+	}
+
+	err = ConfigURLReader{URL: server.URL + "/paasta/missing.json"}.Read(&actual)
+	if !errors.Is(err, fs.ErrNotExist) {
+		test.Errorf("expected not exist error, got: %v", err)
+	}
+
+	dir := test.TempDir()
+	writeFile(test, path.Join(dir, "foo.json"), `{"foo": "local"}`)
+	reader := ChainedConfigReader{Readers: []ConfigReader{
+		ConfigURLReader{URL: server.URL + "/paasta/missing.json"},
+		ConfigURLReader{URL: "file://" + path.Join(dir, "foo.json")},
+	}}
+	if err := reader.Read(&actual); err != nil || actual.Foo != "local" {
+		test.Errorf("failed to fall back to file url, got: %+v, err: %v", actual, err)
+	}
+
+	err = ConfigURLReader{URL: "ftp://example.com/foo.json"}.Read(&actual)
+	if err == nil {
+		test.Errorf("expected unsupported scheme error")
+	}
+}
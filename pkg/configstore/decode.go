@@ -0,0 +1,245 @@
+package configstore
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Yelp/paasta-tools-go/pkg/containerspec"
+	"github.com/mitchellh/mapstructure"
+)
+
+// Validator checks a config value after it was decoded by `Load` or
+// `LoadStrict`, it receives the destination pointer passed to them
+type Validator func(value interface{}) error
+
+// FieldError can be returned by validators to point at the invalid field,
+// `Field` is a dotted path relative to the validated value
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Field, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError is returned when a config value fails to decode strictly or
+// to validate, `Path` is a dotted path starting with the config key
+type ValidationError struct {
+	File string
+	Path string
+	Err  error
+}
+
+func (e *ValidationError) Error() string {
+	if e.File == "" {
+		return fmt.Sprintf("invalid config %s: %v", e.Path, e.Err)
+	}
+	return fmt.Sprintf("invalid config %s in %s: %v", e.Path, e.File, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+var kubeResourceQuantityType = reflect.TypeOf(containerspec.KubeResourceQuantity(""))
+
+// numberToKubeResourceQuantityHook allows resources like `cpus: 0.5` to be
+// decoded into `containerspec.KubeResourceQuantity`
+func numberToKubeResourceQuantityHook(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+	if to != kubeResourceQuantityType {
+		return data, nil
+	}
+	switch v := data.(type) {
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	}
+	return data, nil
+}
+
+// stringToIntHook parses strings decoded into integer fields
+func stringToIntHook(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+	if from.Kind() != reflect.String {
+		return data, nil
+	}
+	switch to.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.ParseInt(strings.TrimSpace(data.(string)), 0, to.Bits())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.ParseUint(strings.TrimSpace(data.(string)), 0, to.Bits())
+	}
+	return data, nil
+}
+
+// hasMapstructureTags reports whether a struct reachable from t has fields
+// with `mapstructure` tags, `seen` guards against recursive types
+func hasMapstructureTags(t reflect.Type, seen map[reflect.Type]bool) bool {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || seen[t] {
+		return false
+	}
+	seen[t] = true
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if _, ok := field.Tag.Lookup("mapstructure"); ok {
+			return true
+		}
+		if hasMapstructureTags(field.Type, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeTagName returns the struct tag fields of dst are matched by: the
+// `mapstructure` tag when dst uses it, the `json` tag otherwise, since most
+// config types of this repo, like `containerspec.PaastaContainerSpec`, only
+// have json tags
+func decodeTagName(dst interface{}) string {
+	if hasMapstructureTags(reflect.TypeOf(dst), map[reflect.Type]bool{}) {
+		return "mapstructure"
+	}
+	return "json"
+}
+
+// decodeStrict decodes val into dst failing on unknown keys. Embedded
+// structs are squashed, like `encoding/json` does.
+func decodeStrict(val interface{}, dst interface{}) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		ErrorUnused: true,
+		Squash:      true,
+		TagName:     decodeTagName(dst),
+		Result:      dst,
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			numberToKubeResourceQuantityHook,
+			stringToIntHook,
+		),
+	})
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(val)
+}
+
+var mapstructureFieldRegex = regexp.MustCompile(`^'([^']*)'`)
+
+// qualifyDecodeError prefixes field names in mapstructure errors with `key`
+func qualifyDecodeError(key string, err error) error {
+	msErr, ok := err.(*mapstructure.Error)
+	if !ok {
+		return err
+	}
+	errors := make([]string, len(msErr.Errors))
+	for idx, msg := range msErr.Errors {
+		errors[idx] = mapstructureFieldRegex.ReplaceAllStringFunc(msg, func(field string) string {
+			field = strings.Trim(field, "'")
+			if field == "" {
+				return fmt.Sprintf("'%s'", key)
+			}
+			return fmt.Sprintf("'%s.%s'", key, field)
+		})
+	}
+	return fmt.Errorf("%s", strings.Join(errors, ", "))
+}
+
+// AddValidator registers a validator run by `Load` and `LoadStrict` for `key`
+func (s *Store) AddValidator(key string, validator Validator) {
+	s.Lock()
+	defer s.Unlock()
+	if s.validators == nil {
+		s.validators = map[string][]Validator{}
+	}
+	s.validators[key] = append(s.validators[key], validator)
+}
+
+func (s *Store) validate(key string, dst interface{}) error {
+	s.Lock()
+	validators := s.validators[key]
+	s.Unlock()
+	return runValidators(validators, key, dst, func() string {
+		prov, _ := s.Where(key)
+		return prov.File
+	})
+}
+
+// AddValidator registers a validator run by `Load` and `LoadStrict` for `key`
+func (s *LayeredStore) AddValidator(key string, validator Validator) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.validators == nil {
+		s.validators = map[string][]Validator{}
+	}
+	s.validators[key] = append(s.validators[key], validator)
+}
+
+func (s *LayeredStore) validate(key string, dst interface{}) error {
+	s.lock.Lock()
+	validators := s.validators[key]
+	s.lock.Unlock()
+	return runValidators(validators, key, dst, func() string { return "" })
+}
+
+// runValidators runs `validators` of `key` on dst, `file` returns the file
+// reported in validation errors
+func runValidators(validators []Validator, key string, dst interface{}, file func() string) error {
+	for _, validator := range validators {
+		if err := validator(dst); err != nil {
+			path := key
+			if fieldErr, ok := err.(*FieldError); ok {
+				path = key + "." + fieldErr.Field
+				err = fieldErr.Err
+			}
+			return &ValidationError{File: file(), Path: path, Err: err}
+		}
+	}
+	return nil
+}
+
+// LoadStrict works like `Load`, but fails on keys which don't map to any field
+// of dst. Fields are matched by `mapstructure` tags, or by `json` tags when
+// dst doesn't use mapstructure tags at all. Strings are decoded into
+// `time.Duration` and integer fields, numbers into
+// `containerspec.KubeResourceQuantity`. Errors are `*ValidationError`.
+func (s *Store) LoadStrict(key string, dst interface{}) (bool, error) {
+	val, ok, err := s.Get(key)
+	if err != nil {
+		return false, fmt.Errorf("Failed to get %s: %v", key, err)
+	}
+	if !ok {
+		return false, nil
+	}
+	if err := decodeStrict(val, dst); err != nil {
+		prov, _ := s.Where(key)
+		return true, &ValidationError{File: prov.File, Path: key, Err: qualifyDecodeError(key, err)}
+	}
+	return true, s.validate(key, dst)
+}
+
+// LoadStrict works like `Store.LoadStrict` on the value returned by `Get`
+func (s *LayeredStore) LoadStrict(key string, dst interface{}) (bool, error) {
+	val, ok, err := s.Get(key)
+	if err != nil {
+		return false, fmt.Errorf("Failed to get %s: %v", key, err)
+	}
+	if !ok {
+		return false, nil
+	}
+	if err := decodeStrict(val, dst); err != nil {
+		return true, &ValidationError{Path: key, Err: qualifyDecodeError(key, err)}
+	}
+	return true, s.validate(key, dst)
+}
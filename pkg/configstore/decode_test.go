@@ -0,0 +1,145 @@
+package configstore
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Yelp/paasta-tools-go/pkg/containerspec"
+)
+
+type strictTestConfig struct {
+	Timeout time.Duration                      `mapstructure:"timeout"`
+	Port    int                                `mapstructure:"port"`
+	CPU     containerspec.KubeResourceQuantity `mapstructure:"cpus"`
+	Memory  containerspec.KubeResourceQuantity `mapstructure:"mem"`
+	Nested  struct {
+		Name string `mapstructure:"name"`
+	} `mapstructure:"nested"`
+}
+
+func TestStore_LoadStrict(test *testing.T) {
+	dir := test.TempDir()
+	writeConfig(test, path.Join(dir, "good.yaml"), `
+good:
+  timeout: 30s
+  port: "8080"
+  cpus: 0.5
+  mem: 512
+  nested:
+    name: foo
+`, time.Now())
+	writeConfig(test, path.Join(dir, "typo.yaml"), `
+typo:
+  port: 80
+  nested:
+    name: foo
+    nmae: bar
+`, time.Now())
+	s := NewStore(dir, nil)
+
+	conf := strictTestConfig{}
+	ok, err := s.LoadStrict("good", &conf)
+	errorIf(test, !ok || err != nil, "failed to load good: %v", err)
+	errorUnexpected(test, 30*time.Second, conf.Timeout)
+	errorUnexpected(test, 8080, conf.Port)
+	errorUnexpected(test, containerspec.KubeResourceQuantity("0.5"), conf.CPU)
+	errorUnexpected(test, containerspec.KubeResourceQuantity("512"), conf.Memory)
+	errorUnexpected(test, "foo", conf.Nested.Name)
+
+	// non-strict load ignores typos
+	conf = strictTestConfig{}
+	ok, err = s.Load("typo", &conf)
+	errorIf(test, !ok || err != nil, "failed to load typo: %v", err)
+
+	ok, err = s.LoadStrict("typo", &conf)
+	errorIf(test, !ok, "typo not found")
+	validationErr := &ValidationError{}
+	errorIf(test, !errors.As(err, &validationErr), "expected ValidationError, got %v", err)
+	errorUnexpected(test, path.Join(dir, "typo.yaml"), validationErr.File)
+	errorIf(
+		test,
+		!strings.Contains(err.Error(), "'typo.nested' has invalid keys: nmae"),
+		"error isn't path qualified: %v", err,
+	)
+
+	ok, err = s.LoadStrict("missing", &conf)
+	errorIf(test, ok || err != nil, "unexpected result for missing key: %v", err)
+}
+
+func TestStore_LoadStrictJSONTags(test *testing.T) {
+	s := storeWith(map[string]interface{}{
+		"spec":  map[string]interface{}{"cpus": 0.5, "mem": 512, "disk_limit": "2Gi"},
+		"typo":  map[string]interface{}{"cpus": 0.5, "memory": 512},
+		"embed": map[string]interface{}{"cpus": 1, "name": "main"},
+	})
+
+	spec := containerspec.PaastaContainerSpec{}
+	ok, err := s.LoadStrict("spec", &spec)
+	errorIf(test, !ok || err != nil, "failed to load spec: %v", err)
+	errorUnexpected(test, containerspec.KubeResourceQuantity("0.5"), *spec.CPU)
+	errorUnexpected(test, containerspec.KubeResourceQuantity("512"), *spec.Memory)
+	errorUnexpected(test, containerspec.KubeResourceQuantity("2Gi"), *spec.DiskLimit)
+
+	_, err = s.LoadStrict("typo", &containerspec.PaastaContainerSpec{})
+	errorIf(
+		test,
+		err == nil || !strings.Contains(err.Error(), "'typo' has invalid keys: memory"),
+		"expected invalid key error, got %v", err,
+	)
+
+	embedding := struct {
+		containerspec.PaastaContainerSpec
+		Name string `json:"name"`
+	}{}
+	ok, err = s.LoadStrict("embed", &embedding)
+	errorIf(test, !ok || err != nil, "failed to load embed: %v", err)
+	errorUnexpected(test, containerspec.KubeResourceQuantity("1"), *embedding.CPU)
+	errorUnexpected(test, "main", embedding.Name)
+}
+
+func TestStore_AddValidator(test *testing.T) {
+	s := storeWith(map[string]interface{}{
+		"good": map[string]interface{}{"port": 80, "nested": map[string]interface{}{"name": "foo"}},
+		"bad":  map[string]interface{}{"port": 80, "nested": map[string]interface{}{"name": ""}},
+	})
+	validator := func(value interface{}) error {
+		conf := value.(*strictTestConfig)
+		if conf.Nested.Name == "" {
+			return &FieldError{Field: "nested.name", Err: fmt.Errorf("must not be empty")}
+		}
+		return nil
+	}
+	s.AddValidator("good", validator)
+	s.AddValidator("bad", validator)
+
+	conf := strictTestConfig{}
+	_, err := s.Load("good", &conf)
+	errorIf(test, err != nil, "failed to load good: %v", err)
+
+	_, err = s.LoadStrict("bad", &conf)
+	errorUnexpected(test, "invalid config bad.nested.name: must not be empty", fmt.Sprint(err))
+	_, err = s.Load("bad", &conf)
+	errorUnexpected(test, "invalid config bad.nested.name: must not be empty", fmt.Sprint(err))
+}
+
+func TestLayeredStore_AddValidator(test *testing.T) {
+	s := NewLayeredStore(storeWith(map[string]interface{}{
+		"bad": map[string]interface{}{"port": 80, "nested": map[string]interface{}{"name": ""}},
+	}))
+	s.AddValidator("bad", func(value interface{}) error {
+		if value.(*strictTestConfig).Nested.Name == "" {
+			return &FieldError{Field: "nested.name", Err: fmt.Errorf("must not be empty")}
+		}
+		return nil
+	})
+
+	conf := strictTestConfig{}
+	_, err := s.LoadStrict("bad", &conf)
+	errorUnexpected(test, "invalid config bad.nested.name: must not be empty", fmt.Sprint(err))
+	_, err = s.Load("bad", &conf)
+	errorUnexpected(test, "invalid config bad.nested.name: must not be empty", fmt.Sprint(err))
+}
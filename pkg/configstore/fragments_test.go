@@ -0,0 +1,67 @@
+package configstore
+
+import (
+	"os"
+	"path"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStore_FragmentHints(test *testing.T) {
+	dir := test.TempDir()
+	now := time.Now()
+	for _, sub := range []string{"volumes.d", "limits.d"} {
+		if err := os.Mkdir(path.Join(dir, sub), 0755); err != nil {
+			test.Fatal(err)
+		}
+	}
+	writeConfig(test, path.Join(dir, "volumes.d", "20-extra.yml"), `
+volumes:
+  extra: {hostPath: /extra}
+`, now)
+	writeConfig(test, path.Join(dir, "volumes.d", "10-base.yaml"), `
+volumes:
+  base: {hostPath: /base}
+  extra: {hostPath: /overridden}
+`, now)
+	writeConfig(test, path.Join(dir, "volumes.d", "README"), "not a config", now)
+	writeConfig(test, path.Join(dir, "limits.d", "10-base.json"), `{"limits": {"cpu": 1}}`, now)
+	writeConfig(test, path.Join(dir, "limits.d", "20-host.yaml"), "limits: {cpu: 2}\n", now)
+	writeConfig(test, path.Join(dir, "registry.yml"), "registry: docker.yelp.com\n", now)
+
+	s := NewStore(dir, map[string]string{
+		"volumes": "volumes.d",
+		"limits":  "limits.d/*.json",
+	})
+	s.DeepMerge = true
+
+	val, ok, err := s.Get("volumes")
+	errorIf(test, !ok || err != nil, "failed to get volumes: %v", err)
+	expected := map[string]interface{}{
+		"base":  map[interface{}]interface{}{"hostPath": "/base"},
+		"extra": map[string]interface{}{"hostPath": "/extra"},
+	}
+	errorIf(test, !reflect.DeepEqual(expected, val), "unexpected volumes: %#v", val)
+	prov, _ := s.Where("volumes")
+	errorUnexpected(test, path.Join(dir, "volumes.d", "20-extra.yml"), prov.File)
+
+	val, ok, err = s.Get("limits")
+	errorIf(test, !ok || err != nil, "failed to get limits: %v", err)
+	errorIf(
+		test, !reflect.DeepEqual(map[string]interface{}{"cpu": float64(1)}, val),
+		"pattern matched unexpected files: %#v", val,
+	)
+
+	val, ok, err = s.Get("registry")
+	errorIf(test, !ok || err != nil, "failed to get registry: %v", err)
+	errorUnexpected(test, "docker.yelp.com", val)
+
+	// directories are skipped when loading all files
+	_, ok, err = s.Get("missing")
+	errorIf(test, ok || err != nil, "unexpected result for missing: %v", err)
+
+	s.AddHint("broken", "*.d/*.yaml")
+	_, _, err = s.Get("broken")
+	errorIf(test, err == nil, "expected error for pattern in directory")
+}
@@ -0,0 +1,70 @@
+package configstore
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sync"
+)
+
+// NewStoreFromFS creates a config store loading files from `dir` in `fsys`,
+// e.g. an `embed.FS` or `fstest.MapFS`. `dir` and hints are slash-separated
+// paths as required by `io/fs`, use "." for the root of `fsys`.
+func NewStoreFromFS(fsys fs.FS, dir string, hints map[string]string) *Store {
+	s := NewStore(dir, hints)
+	s.ListFiles = func(dirname string) ([]string, error) {
+		entries, err := fs.ReadDir(fsys, dirname)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to list directory %v: %v", dirname, err)
+		}
+		ret := make([]string, len(entries))
+		for idx, entry := range entries {
+			ret[idx] = entry.Name()
+		}
+		return ret, nil
+	}
+	s.ParseFile = func(filepath string, value interface{}) error {
+		reader, err := fsys.Open(filepath)
+		if err != nil {
+			return fmt.Errorf("Failed to open %s: %v", filepath, err)
+		}
+		defer reader.Close()
+		return decode(reader, path.Ext(filepath), value)
+	}
+	s.FileExists = func(filepath string) (bool, error) {
+		_, err := fs.Stat(fsys, filepath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return false, nil
+			}
+			return false, err
+		}
+		return true, nil
+	}
+	s.StatFile = func(filepath string) (os.FileInfo, error) {
+		return fs.Stat(fsys, filepath)
+	}
+	s.ReadFile = func(filepath string) ([]byte, error) {
+		return fs.ReadFile(fsys, filepath)
+	}
+	return s
+}
+
+// NewStoreFromMap creates a config store serving values from `data` without
+// touching disk, keys missing from `data` are never found
+func NewStoreFromMap(data map[string]interface{}) *Store {
+	s := &Store{
+		Data:       &sync.Map{},
+		Hints:      map[string]string{},
+		ListFiles:  func(string) ([]string, error) { return []string{}, nil },
+		ParseFile:  func(filepath string, _ interface{}) error { return fmt.Errorf("unexpected parse of %s", filepath) },
+		FileExists: func(string) (bool, error) { return false, nil },
+		StatFile:   func(string) (os.FileInfo, error) { return nil, os.ErrNotExist },
+		inMemory:   true,
+	}
+	for key, val := range data {
+		s.Data.Store(key, val)
+	}
+	return s
+}
@@ -0,0 +1,265 @@
+package configstore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// IndexFileName is the name of an index supplied next to config files
+const IndexFileName = ".index.json"
+
+const indexVersion = 1
+
+// IndexedFile describes keys defined in a config file at the time it was
+// indexed
+type IndexedFile struct {
+	ModTime time.Time `json:"mod_time"`
+	Size    int64     `json:"size"`
+	Keys    []string  `json:"keys"`
+}
+
+// Index maps config files in a directory to keys they define, so that keys
+// missing from hints can be found without parsing every file. `Files` are
+// keyed by file names relative to the directory.
+type Index struct {
+	Version int                     `json:"version"`
+	Files   map[string]*IndexedFile `json:"files"`
+}
+
+// FilesFor returns names of files defining `key` in lexical order
+func (idx *Index) FilesFor(key string) []string {
+	files := []string{}
+	for name, file := range idx.Files {
+		if contains(file.Keys, key) {
+			files = append(files, name)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// ReadIndex reads an index written by `WriteIndex`
+func ReadIndex(filepath string) (*Index, error) {
+	data, err := os.ReadFile(filepath)
+	if err != nil {
+		return nil, err
+	}
+	return parseIndex(filepath, data)
+}
+
+func parseIndex(filepath string, data []byte) (*Index, error) {
+	idx := &Index{}
+	if err := json.Unmarshal(data, idx); err != nil {
+		return nil, fmt.Errorf("Failed to parse %s: %v", filepath, err)
+	}
+	if idx.Version != indexVersion {
+		return nil, fmt.Errorf("unsupported index version %d in %s", idx.Version, filepath)
+	}
+	return idx, nil
+}
+
+// WriteIndex atomically writes `idx` to `filepath`, creating parent
+// directories if needed
+func WriteIndex(filepath string, idx *Index) error {
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(path.Dir(filepath), 0755); err != nil {
+		return fmt.Errorf("Failed to create %s: %v", path.Dir(filepath), err)
+	}
+	tmp, err := os.CreateTemp(path.Dir(filepath), path.Base(filepath)+".tmp")
+	if err != nil {
+		return fmt.Errorf("Failed to create temporary file for %s: %v", filepath, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("Failed to write %s: %v", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("Failed to write %s: %v", tmp.Name(), err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath)
+}
+
+// IndexCachePath returns where the index of `dir` is persisted in `cacheDir`
+func IndexCachePath(cacheDir, dir string) string {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	name := strings.ReplaceAll(strings.Trim(path.Clean(dir), "/"), "/", "_")
+	return path.Join(cacheDir, name+IndexFileName)
+}
+
+// indexPaths returns paths where an index of s.Dir may be found, the index
+// supplied with config files takes priority over the cached one
+func (s *Store) indexPaths() []string {
+	paths := []string{path.Join(s.Dir, IndexFileName)}
+	if s.IndexCacheDir != "" {
+		paths = append(paths, IndexCachePath(s.IndexCacheDir, s.Dir))
+	}
+	return paths
+}
+
+// readIndex reads the index supplied in s.Dir with s.ReadFile, or the cached
+// one from disk
+func (s *Store) readIndex(filepath string) (*Index, error) {
+	if s.ReadFile == nil || filepath != path.Join(s.Dir, IndexFileName) {
+		return ReadIndex(filepath)
+	}
+	data, err := s.ReadFile(filepath)
+	if err != nil {
+		return nil, err
+	}
+	return parseIndex(filepath, data)
+}
+
+// listConfigFiles lists s.Dir without the index file and directories
+func (s *Store) listConfigFiles() ([]string, error) {
+	files, err := s.ListFiles(s.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list %s: %v", s.Dir, err)
+	}
+	res := make([]string, 0, len(files))
+	for _, file := range files {
+		if file != IndexFileName && !s.isDir(path.Join(s.Dir, file)) {
+			res = append(res, file)
+		}
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+// fresh checks that `idx` lists all files in s.Dir and none of them changed
+// since it was built
+func (s *Store) fresh(idx *Index) bool {
+	files, err := s.listConfigFiles()
+	if err != nil || len(files) != len(idx.Files) {
+		return false
+	}
+	for _, name := range files {
+		indexed, ok := idx.Files[name]
+		if !ok {
+			return false
+		}
+		file, err := s.stat(path.Join(s.Dir, name))
+		if err != nil || !file.modTime.Equal(indexed.ModTime) || file.size != indexed.Size {
+			return false
+		}
+	}
+	return true
+}
+
+// freshIndex returns an up to date index of s.Dir, or nil when indexing is
+// disabled or there is no usable index
+func (s *Store) freshIndex() *Index {
+	if !s.UseIndex {
+		return nil
+	}
+	s.Lock()
+	idx := s.index
+	s.Unlock()
+	if idx != nil && s.fresh(idx) {
+		return idx
+	}
+
+	for _, filepath := range s.indexPaths() {
+		idx, err := s.readIndex(filepath)
+		if err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				log.Printf("WARN: ignoring config index %s: %v", filepath, err)
+			}
+			continue
+		}
+		if !s.fresh(idx) {
+			log.Printf("WARN: ignoring stale config index %s", filepath)
+			continue
+		}
+		s.Lock()
+		s.index = idx
+		s.Unlock()
+		return idx
+	}
+	return nil
+}
+
+// buildIndex creates an index from files tracked after `loadAll`, it returns
+// nil if some file in s.Dir wasn't tracked
+func (s *Store) buildIndex() (*Index, error) {
+	files, err := s.listConfigFiles()
+	if err != nil {
+		return nil, err
+	}
+	s.Lock()
+	defer s.Unlock()
+	idx := &Index{Version: indexVersion, Files: map[string]*IndexedFile{}}
+	for _, name := range files {
+		loaded, ok := s.files[path.Join(s.Dir, name)]
+		if !ok {
+			return nil, fmt.Errorf("%s wasn't loaded", path.Join(s.Dir, name))
+		}
+		keys := make([]string, 0, len(loaded.values))
+		for key := range loaded.values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		idx.Files[name] = &IndexedFile{ModTime: loaded.modTime, Size: loaded.size, Keys: keys}
+	}
+	return idx, nil
+}
+
+// updateIndex rebuilds the index after `loadAll` and persists it in
+// s.IndexCacheDir, failures are only logged since the index is an optimization
+func (s *Store) updateIndex() {
+	if !s.UseIndex {
+		return
+	}
+	idx, err := s.buildIndex()
+	if err != nil {
+		log.Printf("WARN: failed to index configs in %s: %v", s.Dir, err)
+		return
+	}
+	s.Lock()
+	s.index = idx
+	s.Unlock()
+	if s.IndexCacheDir == "" {
+		return
+	}
+	filepath := IndexCachePath(s.IndexCacheDir, s.Dir)
+	if err := WriteIndex(filepath, idx); err != nil {
+		log.Printf("WARN: failed to write config index %s: %v", filepath, err)
+	}
+}
+
+// loadIndexed loads files which define `key` according to `idx`
+func (s *Store) loadIndexed(idx *Index, key string) error {
+	for _, name := range idx.FilesFor(key) {
+		filepath := path.Join(s.Dir, name)
+		if err := s.loadPath(filepath); err != nil {
+			return fmt.Errorf("Failed to load %s: %v", filepath, err)
+		}
+	}
+	return nil
+}
+
+// BuildIndex loads all files in s.Dir and returns their index, it can be
+// written next to the files with `WriteIndex` as `IndexFileName`
+func (s *Store) BuildIndex() (*Index, error) {
+	if err := s.loadAll(); err != nil {
+		return nil, fmt.Errorf("failed to load all configs: %v", err)
+	}
+	return s.buildIndex()
+}
@@ -0,0 +1,103 @@
+package configstore
+
+import (
+	"os"
+	"path"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func recordParsedFiles(s *Store) *[]string {
+	parsed := []string{}
+	s.ParseFile = func(filepath string, value interface{}) error {
+		parsed = append(parsed, path.Base(filepath))
+		return parseFile(filepath, value)
+	}
+	return &parsed
+}
+
+func TestStore_IndexCache(test *testing.T) {
+	dir := test.TempDir()
+	cacheDir := test.TempDir()
+	start := time.Now().Add(-time.Hour)
+	writeConfig(test, path.Join(dir, "one.json"), `{"one": 1}`, start)
+	writeConfig(test, path.Join(dir, "two.yaml"), "two: 2\nshared: 2\n", start)
+	writeConfig(test, path.Join(dir, "three.json"), `{"shared": 3}`, start)
+
+	s := NewStore(dir, nil)
+	s.UseIndex = true
+	s.IndexCacheDir = cacheDir
+	_, ok, err := s.Get("missing")
+	errorIf(test, ok || err != nil, "unexpected result for missing: %v", err)
+	idx, err := ReadIndex(IndexCachePath(cacheDir, dir))
+	errorIf(test, err != nil, "index wasn't persisted: %v", err)
+	errorUnexpected(test, 3, len(idx.Files))
+	errorIf(
+		test, !reflect.DeepEqual([]string{"three.json", "two.yaml"}, idx.FilesFor("shared")),
+		"unexpected files for shared: %v", idx.FilesFor("shared"),
+	)
+
+	s = NewStore(dir, nil)
+	s.UseIndex = true
+	s.IndexCacheDir = cacheDir
+	parsed := recordParsedFiles(s)
+	val, ok, err := s.Get("shared")
+	errorIf(test, !ok || err != nil, "failed to get shared: %v", err)
+	errorUnexpected(test, 2, val)
+	val, _, _ = s.Get("two")
+	errorUnexpected(test, 2, val)
+	_, ok, _ = s.Get("missing")
+	errorIf(test, ok, "missing was found")
+	errorIf(
+		test, !reflect.DeepEqual([]string{"three.json", "two.yaml"}, *parsed),
+		"unexpected files parsed: %v", *parsed,
+	)
+
+	// stale index is ignored and rebuilt
+	writeConfig(test, path.Join(dir, "one.json"), `{"one": 1, "four": 4}`, start.Add(time.Minute))
+	s = NewStore(dir, nil)
+	s.UseIndex = true
+	s.IndexCacheDir = cacheDir
+	val, ok, err = s.Get("four")
+	errorIf(test, !ok || err != nil, "failed to get four: %v", err)
+	errorUnexpected(test, float64(4), val)
+	idx, _ = ReadIndex(IndexCachePath(cacheDir, dir))
+	errorIf(test, !reflect.DeepEqual([]string{"one.json"}, idx.FilesFor("four")), "index wasn't rebuilt")
+}
+
+func TestStore_SuppliedIndex(test *testing.T) {
+	dir := test.TempDir()
+	writeConfig(test, path.Join(dir, "one.json"), `{"one": 1}`, time.Now())
+	writeConfig(test, path.Join(dir, "two.json"), `{"two": 2}`, time.Now())
+
+	idx, err := NewStore(dir, nil).BuildIndex()
+	errorIf(test, err != nil, "failed to build index: %v", err)
+	err = WriteIndex(path.Join(dir, IndexFileName), idx)
+	errorIf(test, err != nil, "failed to write index: %v", err)
+
+	// the index is only used when enabled
+	s := NewStore(dir, nil)
+	parsed := recordParsedFiles(s)
+	_, ok, err := s.Get("missing")
+	errorIf(test, ok || err != nil, "unexpected result for missing: %v", err)
+	errorIf(test, !reflect.DeepEqual([]string{"one.json", "two.json"}, *parsed), "unexpected files parsed: %v", *parsed)
+
+	s = NewStore(dir, nil)
+	s.UseIndex = true
+	parsed = recordParsedFiles(s)
+	val, ok, err := s.Get("two")
+	errorIf(test, !ok || err != nil, "failed to get two: %v", err)
+	errorUnexpected(test, float64(2), val)
+	errorIf(test, !reflect.DeepEqual([]string{"two.json"}, *parsed), "unexpected files parsed: %v", *parsed)
+
+	// the index itself is never loaded as config
+	s = NewStore(dir, nil)
+	_, ok, err = s.Get("files")
+	errorIf(test, ok || err != nil, "index was loaded as config: %v", err)
+
+	os.Remove(path.Join(dir, "one.json"))
+	s = NewStore(dir, nil)
+	s.UseIndex = true
+	errorIf(test, s.freshIndex() != nil, "index is stale after removing a file")
+}
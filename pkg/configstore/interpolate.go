@@ -0,0 +1,153 @@
+package configstore
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// RedactedValue replaces values with placeholders in snapshots of stores
+// with `Interpolator.Redact`
+const RedactedValue = "<redacted>"
+
+// ErrInterpolationCycle is returned when placeholders resolve to each other
+var ErrInterpolationCycle = errors.New("interpolation cycle")
+
+// ErrInterpolationMissing is returned when a placeholder references a missing
+// environment variable or file
+var ErrInterpolationMissing = errors.New("interpolated value not found")
+
+var placeholderRegex = regexp.MustCompile(`\$\{(ENV|FILE):([^}]+)\}`)
+
+// Interpolator resolves `${ENV:NAME}` and `${FILE:/path}` placeholders in
+// string values returned by `Store.Get`. Resolved values may contain other
+// placeholders, which are resolved recursively. Trailing newlines are
+// stripped from file contents, e.g. tokens mounted by projected volumes.
+type Interpolator struct {
+	LookupEnv func(string) (string, bool)
+	ReadFile  func(string) ([]byte, error)
+
+	// Redact makes `Store.Snapshot` print `RedactedValue` in place of values
+	// with placeholders instead of resolving them
+	Redact bool
+}
+
+// NewInterpolator creates an interpolator reading the process environment
+// and local files, with redaction enabled
+func NewInterpolator() *Interpolator {
+	return &Interpolator{
+		LookupEnv: os.LookupEnv,
+		ReadFile:  os.ReadFile,
+		Redact:    true,
+	}
+}
+
+func (i *Interpolator) lookup(kind, name string) (string, error) {
+	switch kind {
+	case "ENV":
+		lookupEnv := i.LookupEnv
+		if lookupEnv == nil {
+			lookupEnv = os.LookupEnv
+		}
+		val, ok := lookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("%w: environment variable %s", ErrInterpolationMissing, name)
+		}
+		return val, nil
+	default:
+		readFile := i.ReadFile
+		if readFile == nil {
+			readFile = os.ReadFile
+		}
+		data, err := readFile(name)
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("%w: file %s", ErrInterpolationMissing, name)
+		}
+		if err != nil {
+			return "", fmt.Errorf("Failed to read %s: %v", name, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+}
+
+// resolveString replaces placeholders in `str`, `stack` holds placeholders
+// being resolved to detect cycles
+func (i *Interpolator) resolveString(str string, stack []string) (string, error) {
+	var resolveErr error
+	res := placeholderRegex.ReplaceAllStringFunc(str, func(placeholder string) string {
+		if resolveErr != nil {
+			return placeholder
+		}
+		if contains(stack, placeholder) {
+			resolveErr = fmt.Errorf(
+				"%w: %s", ErrInterpolationCycle,
+				strings.Join(append(stack, placeholder), " -> "),
+			)
+			return placeholder
+		}
+		match := placeholderRegex.FindStringSubmatch(placeholder)
+		val, err := i.lookup(match[1], match[2])
+		if err != nil {
+			resolveErr = err
+			return placeholder
+		}
+		val, err = i.resolveString(val, append(stack, placeholder))
+		if err != nil {
+			resolveErr = err
+			return placeholder
+		}
+		return val
+	})
+	return res, resolveErr
+}
+
+// Resolve returns a copy of `value` with placeholders in all strings
+// resolved, dictionaries and lists are walked recursively
+func (i *Interpolator) Resolve(value interface{}) (interface{}, error) {
+	return i.walk(value, func(str string) (interface{}, error) {
+		return i.resolveString(str, nil)
+	})
+}
+
+// redact returns a copy of `value` with strings containing placeholders
+// replaced by `RedactedValue`
+func (i *Interpolator) redact(value interface{}) interface{} {
+	res, _ := i.walk(value, func(str string) (interface{}, error) {
+		if placeholderRegex.MatchString(str) {
+			return RedactedValue, nil
+		}
+		return str, nil
+	})
+	return res
+}
+
+func (i *Interpolator) walk(value interface{}, fn func(string) (interface{}, error)) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return fn(v)
+	case []interface{}:
+		res := make([]interface{}, len(v))
+		for idx, val := range v {
+			resolved, err := i.walk(val, fn)
+			if err != nil {
+				return nil, err
+			}
+			res[idx] = resolved
+		}
+		return res, nil
+	}
+	if m, ok := toStringMap(value); ok {
+		res := make(map[string]interface{}, len(m))
+		for key, val := range m {
+			resolved, err := i.walk(val, fn)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			res[key] = resolved
+		}
+		return res, nil
+	}
+	return value, nil
+}
@@ -0,0 +1,69 @@
+package configstore
+
+import (
+	"errors"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+)
+
+func TestStore_Interpolation(test *testing.T) {
+	dir := test.TempDir()
+	tokenPath := path.Join(dir, "token")
+	if err := os.WriteFile(tokenPath, []byte("s3cr3t\n"), 0600); err != nil {
+		test.Fatal(err)
+	}
+	env := map[string]string{
+		"CLUSTER": "norcal",
+		"NESTED":  "${ENV:CLUSTER}-prod",
+		"LOOP_A":  "${ENV:LOOP_B}",
+		"LOOP_B":  "${ENV:LOOP_A}",
+	}
+	s := NewStoreFromMap(map[string]interface{}{
+		"auth": map[interface{}]interface{}{
+			"token":   "${FILE:" + tokenPath + "}",
+			"cluster": "${ENV:CLUSTER}",
+			"hosts":   []interface{}{"${ENV:NESTED}.yelp.com", 1},
+			"shell":   "${HOME}",
+		},
+		"cycle":        "${ENV:LOOP_A}",
+		"missing_env":  "${ENV:UNSET}",
+		"missing_file": "${FILE:" + path.Join(dir, "missing") + "}",
+	})
+
+	// values are returned as they are without interpolator
+	val, _, _ := s.Get("cycle")
+	errorUnexpected(test, "${ENV:LOOP_A}", val)
+
+	s.Interpolator = NewInterpolator()
+	s.Interpolator.LookupEnv = func(name string) (string, bool) {
+		val, ok := env[name]
+		return val, ok
+	}
+	val, ok, err := s.Get("auth")
+	errorIf(test, !ok || err != nil, "failed to get auth: %v", err)
+	expected := map[string]interface{}{
+		"token":   "s3cr3t",
+		"cluster": "norcal",
+		"hosts":   []interface{}{"norcal-prod.yelp.com", 1},
+		"shell":   "${HOME}",
+	}
+	errorIf(test, !reflect.DeepEqual(expected, val), "unexpected auth: %#v", val)
+
+	_, _, err = s.Get("cycle")
+	errorIf(test, !errors.Is(err, ErrInterpolationCycle), "expected cycle error, got %v", err)
+	_, _, err = s.Get("missing_env")
+	errorIf(test, !errors.Is(err, ErrInterpolationMissing), "expected missing error, got %v", err)
+	_, _, err = s.Get("missing_file")
+	errorIf(test, !errors.Is(err, ErrInterpolationMissing), "expected missing error, got %v", err)
+
+	snapshot := s.Snapshot()
+	errorUnexpected(test, RedactedValue, snapshot["auth"].(map[string]interface{})["token"])
+	errorUnexpected(test, RedactedValue, snapshot["cycle"])
+
+	s.Interpolator.Redact = false
+	snapshot = s.Snapshot()
+	errorUnexpected(test, "s3cr3t", snapshot["auth"].(map[string]interface{})["token"])
+	errorUnexpected(test, "${ENV:LOOP_A}", snapshot["cycle"])
+}
@@ -0,0 +1,159 @@
+package configstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// DefaultEnvOverridePrefix is the prefix of environment variables overriding
+// config keys in `LayeredStore`, e.g. `PAASTA_CONFIG_OVERRIDE_DOCKER_REGISTRY`
+// overrides `docker_registry`
+const DefaultEnvOverridePrefix = "PAASTA_CONFIG_OVERRIDE_"
+
+// Reader is the read API shared by `Store` and `LayeredStore`
+type Reader interface {
+	Get(key string) (interface{}, bool, error)
+	Load(key string, dst interface{}) (bool, error)
+}
+
+var _ Reader = (*Store)(nil)
+var _ Reader = (*LayeredStore)(nil)
+
+// LayeredStore composes several stores, e.g. per-host overrides on top of
+// `/etc/paasta` on top of built-in defaults. `Layers` are ordered from the
+// highest priority to the lowest and the first layer which has a key wins,
+// unless `DeepMerge` is set, in which case dictionaries from all layers are
+// merged recursively with higher priority layers overriding leaf values.
+//
+// Environment variables named `EnvPrefix` followed by the upper-cased key take
+// priority over all layers, their values are decoded as JSON if possible and
+// used as plain strings otherwise. Empty `EnvPrefix` disables env overrides.
+type LayeredStore struct {
+	Layers    []Reader
+	DeepMerge bool
+	EnvPrefix string
+
+	LookupEnv func(string) (string, bool)
+
+	lock       sync.Mutex
+	validators map[string][]Validator
+}
+
+// NewLayeredStore creates a new layered store with env overrides enabled,
+// `layers` are ordered from the highest priority to the lowest
+func NewLayeredStore(layers ...Reader) *LayeredStore {
+	return &LayeredStore{
+		Layers:    layers,
+		EnvPrefix: DefaultEnvOverridePrefix,
+		LookupEnv: os.LookupEnv,
+	}
+}
+
+// NewLayeredStoreFromDirs creates a layered store with a `Store` for each of
+// `dirs` which exists, ordered from the highest priority to the lowest
+func NewLayeredStoreFromDirs(hints map[string]string, dirs ...string) (*LayeredStore, error) {
+	layers := []Reader{}
+	for _, dir := range dirs {
+		exists, err := fileExists(dir)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to find %s: %v", dir, err)
+		}
+		if exists {
+			layers = append(layers, NewStore(dir, hints))
+		}
+	}
+	return NewLayeredStore(layers...), nil
+}
+
+func (s *LayeredStore) getEnv(key string) (interface{}, bool) {
+	if s.EnvPrefix == "" {
+		return nil, false
+	}
+	lookupEnv := s.LookupEnv
+	if lookupEnv == nil {
+		lookupEnv = os.LookupEnv
+	}
+	raw, ok := lookupEnv(s.EnvPrefix + strings.ToUpper(key))
+	if !ok {
+		return nil, false
+	}
+	var val interface{}
+	if err := json.Unmarshal([]byte(raw), &val); err != nil {
+		return raw, true
+	}
+	return val, true
+}
+
+// Get returns value for given `key` from the environment or the first layer
+// which has it, or a merge of values from all layers with `DeepMerge`
+func (s *LayeredStore) Get(key string) (interface{}, bool, error) {
+	envVal, envOk := s.getEnv(key)
+	if !s.DeepMerge {
+		if envOk {
+			return envVal, true, nil
+		}
+		for idx, layer := range s.Layers {
+			if layer == nil {
+				continue
+			}
+			val, ok, err := layer.Get(key)
+			if err != nil {
+				return nil, false, fmt.Errorf("Failed to get %s from layer %d: %v", key, idx, err)
+			}
+			if ok {
+				return val, true, nil
+			}
+		}
+		return nil, false, nil
+	}
+
+	var res interface{}
+	found := false
+	// start from the lowest priority so that higher layers are merged on top
+	for idx := len(s.Layers) - 1; idx >= 0; idx-- {
+		if s.Layers[idx] == nil {
+			continue
+		}
+		val, ok, err := s.Layers[idx].Get(key)
+		if err != nil {
+			return nil, false, fmt.Errorf("Failed to get %s from layer %d: %v", key, idx, err)
+		}
+		if !ok {
+			continue
+		}
+		if found {
+			res = deepMerge(res, val, key, nil)
+		} else {
+			res = val
+			found = true
+		}
+	}
+	if envOk {
+		if found {
+			return deepMerge(res, envVal, key, nil), true, nil
+		}
+		return envVal, true, nil
+	}
+	return res, found, nil
+}
+
+// Load uses mapstructure.Decode to parse result of a Get into provided
+// destination value, and runs validators registered for `key`
+func (s *LayeredStore) Load(key string, dst interface{}) (bool, error) {
+	val, ok, err := s.Get(key)
+	if err != nil {
+		return false, fmt.Errorf("Failed to get %s: %v", key, err)
+	}
+	if !ok {
+		return false, nil
+	}
+	if err := mapstructure.Decode(val, dst); err != nil {
+		return true, err
+	}
+	return true, s.validate(key, dst)
+}
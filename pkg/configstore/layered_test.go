@@ -0,0 +1,102 @@
+package configstore
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func storeWith(data map[string]interface{}) *Store {
+	s := &Store{Data: &sync.Map{}}
+	for key, val := range data {
+		s.Data.Store(key, val)
+	}
+	// keys missing from data are looked for in all files
+	s.FileExists = func(string) (bool, error) { return false, nil }
+	s.ListFiles = func(string) ([]string, error) { return []string{}, nil }
+	return s
+}
+
+func TestLayeredStore_Get(test *testing.T) {
+	override := storeWith(map[string]interface{}{
+		"registry": "override.yelp.com",
+		"limits":   map[string]interface{}{"cpu": 2},
+	})
+	system := storeWith(map[string]interface{}{
+		"registry": "system.yelp.com",
+		"cluster":  "norcal",
+		"limits":   map[string]interface{}{"cpu": 1, "mem": 512},
+	})
+	defaults := storeWith(map[string]interface{}{
+		"cluster": "default",
+		"zipkin":  "noop",
+	})
+	env := map[string]string{}
+	s := NewLayeredStore(override, system, defaults)
+	s.LookupEnv = func(key string) (string, bool) {
+		val, ok := env[key]
+		return val, ok
+	}
+
+	for key, expected := range map[string]interface{}{
+		"registry": "override.yelp.com",
+		"cluster":  "norcal",
+		"zipkin":   "noop",
+		"limits":   map[string]interface{}{"cpu": 2},
+	} {
+		val, ok, err := s.Get(key)
+		errorIf(test, !ok || err != nil, "failed to get %s: %v", key, err)
+		errorIf(test, !reflect.DeepEqual(expected, val), "%s: expected %+v, got %+v", key, expected, val)
+	}
+	_, ok, err := s.Get("missing")
+	errorIf(test, ok || err != nil, "unexpected result for missing key: %v", err)
+
+	env["PAASTA_CONFIG_OVERRIDE_CLUSTER"] = "local"
+	env["PAASTA_CONFIG_OVERRIDE_LIMITS"] = `{"mem": 1024}`
+	val, _, _ := s.Get("cluster")
+	errorUnexpected(test, "local", val)
+	val, _, _ = s.Get("limits")
+	expected := map[string]interface{}{"mem": float64(1024)}
+	errorIf(test, !reflect.DeepEqual(expected, val), "expected %+v, got %+v", expected, val)
+
+	s.DeepMerge = true
+	val, _, _ = s.Get("limits")
+	expected = map[string]interface{}{"cpu": 2, "mem": float64(1024)}
+	errorIf(test, !reflect.DeepEqual(expected, val), "expected %+v, got %+v", expected, val)
+
+	delete(env, "PAASTA_CONFIG_OVERRIDE_LIMITS")
+	val, _, _ = s.Get("limits")
+	expected = map[string]interface{}{"cpu": 2, "mem": 512}
+	errorIf(test, !reflect.DeepEqual(expected, val), "expected %+v, got %+v", expected, val)
+
+	s.EnvPrefix = ""
+	val, _, _ = s.Get("cluster")
+	errorUnexpected(test, "norcal", val)
+}
+
+func TestLayeredStore_GetError(test *testing.T) {
+	broken := storeWith(nil)
+	broken.ListFiles = func(dir string) ([]string, error) { return nil, fmt.Errorf("broken") }
+	s := NewLayeredStore(storeWith(nil), broken)
+	_, ok, err := s.Get("key")
+	errorIf(test, ok || err == nil, "expected error")
+}
+
+func TestNewLayeredStoreFromDirs(test *testing.T) {
+	system := test.TempDir()
+	writeConfig(test, path.Join(system, "docker_registry.json"), `{"docker_registry": "system.yelp.com"}`, time.Now())
+	override := test.TempDir()
+	writeConfig(test, path.Join(override, "docker_registry.json"), `{"docker_registry": "override.yelp.com"}`, time.Now())
+
+	s, err := NewLayeredStoreFromDirs(nil, path.Join(override, "missing"), override, system)
+	errorIf(test, err != nil, "failed to create store: %v", err)
+	errorUnexpected(test, 2, len(s.Layers))
+
+	var registry string
+	ok, err := s.Load("docker_registry", &registry)
+	errorIf(test, !ok || err != nil, "failed to load docker_registry: %v", err)
+	errorUnexpected(test, "override.yelp.com", registry)
+}
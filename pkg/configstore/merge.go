@@ -0,0 +1,70 @@
+package configstore
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// toStringMap returns dictionaries decoded from JSON or YAML as
+// map[string]interface{}, yaml.v2 decodes nested dictionaries with
+// interface{} keys
+func toStringMap(value interface{}) (map[string]interface{}, bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return v, true
+	case map[interface{}]interface{}:
+		res := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			res[fmt.Sprint(key)] = val
+		}
+		return res, true
+	default:
+		return nil, false
+	}
+}
+
+// deepMerge merges `src` on top of `dst` and returns the result without
+// modifying either of them. Dictionaries are merged recursively, any other
+// value from `src` replaces the one from `dst`. Paths of replaced values are
+// appended to `conflicts`.
+func deepMerge(dst, src interface{}, path string, conflicts *[]string) interface{} {
+	dstMap, dstOk := toStringMap(dst)
+	srcMap, srcOk := toStringMap(src)
+	if !dstOk || !srcOk {
+		if conflicts != nil && !reflect.DeepEqual(dst, src) {
+			*conflicts = append(*conflicts, path)
+		}
+		return src
+	}
+
+	res := make(map[string]interface{}, len(dstMap)+len(srcMap))
+	for key, val := range dstMap {
+		res[key] = val
+	}
+	for key, val := range srcMap {
+		if old, ok := res[key]; ok {
+			res[key] = deepMerge(old, val, path+"."+key, conflicts)
+		} else {
+			res[key] = val
+		}
+	}
+	return res
+}
+
+// merge combines a cached value with a value loaded later, according to the
+// store merge mode
+func (s *Store) merge(old, new interface{}, key string, conflicts *[]string) interface{} {
+	if !s.DeepMerge {
+		if conflicts != nil && !reflect.DeepEqual(old, new) {
+			*conflicts = append(*conflicts, key)
+		}
+		return new
+	}
+	return deepMerge(old, new, key, conflicts)
+}
+
+// DeepMerge merges `src` on top of `dst` the same way stores with `DeepMerge`
+// do, without modifying either of them
+func DeepMerge(dst, src interface{}) interface{} {
+	return deepMerge(dst, src, "", nil)
+}
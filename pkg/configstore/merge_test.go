@@ -0,0 +1,105 @@
+package configstore
+
+import (
+	"path"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func writeMergeConfigs(test *testing.T) string {
+	dir := test.TempDir()
+	start := time.Now().Add(-time.Hour)
+	writeConfig(test, path.Join(dir, "10-base.json"), `{
+  "cluster": "norcal",
+  "volumes": [{"hostPath": "/a"}],
+  "kubernetes": {"enabled": true, "limits": {"cpu": 1, "mem": 512}}
+}`, start)
+	writeConfig(test, path.Join(dir, "20-override.yaml"), `
+cluster: pnw
+volumes:
+- hostPath: /b
+kubernetes:
+  limits:
+    cpu: 2
+  labels:
+    team: paasta
+`, start)
+	return dir
+}
+
+func TestStore_LoadAllLexicalOrder(test *testing.T) {
+	dir := writeMergeConfigs(test)
+	s := NewStore(dir, nil)
+	// listing order must not matter
+	s.ListFiles = func(string) ([]string, error) {
+		return []string{"20-override.yaml", "10-base.json"}, nil
+	}
+
+	val, _, err := s.Get("cluster")
+	errorIf(test, err != nil, "failed to get cluster: %v", err)
+	errorUnexpected(test, "pnw", val)
+
+	val, _, _ = s.Get("kubernetes")
+	expected := map[interface{}]interface{}{
+		"limits": map[interface{}]interface{}{"cpu": 2},
+		"labels": map[interface{}]interface{}{"team": "paasta"},
+	}
+	errorIf(test, !reflect.DeepEqual(expected, val), "expected %+v, got %+v", expected, val)
+}
+
+func TestStore_DeepMerge(test *testing.T) {
+	dir := writeMergeConfigs(test)
+	s := NewStore(dir, nil)
+	s.DeepMerge = true
+
+	val, _, err := s.Get("kubernetes")
+	errorIf(test, err != nil, "failed to get kubernetes: %v", err)
+	expected := map[string]interface{}{
+		"enabled": true,
+		"limits":  map[string]interface{}{"cpu": 2, "mem": float64(512)},
+		"labels":  map[interface{}]interface{}{"team": "paasta"},
+	}
+	errorIf(test, !reflect.DeepEqual(expected, val), "expected %+v, got %+v", expected, val)
+
+	// lists and scalars are replaced
+	val, _, _ = s.Get("volumes")
+	expectedVolumes := []interface{}{map[interface{}]interface{}{"hostPath": "/b"}}
+	errorIf(test, !reflect.DeepEqual(expectedVolumes, val), "expected %+v, got %+v", expectedVolumes, val)
+	val, _, _ = s.Get("cluster")
+	errorUnexpected(test, "pnw", val)
+
+	// refresh merges all files again
+	writeConfig(test, path.Join(dir, "10-base.json"), `{"kubernetes": {"enabled": false}}`, time.Now())
+	changed, err := s.Refresh()
+	errorIf(test, err != nil, "refresh failed: %v", err)
+	errorIf(test, !reflect.DeepEqual([]string{"kubernetes"}, changed), "unexpected changes %v", changed)
+	val, _, _ = s.Get("kubernetes")
+	expected = map[string]interface{}{
+		"enabled": false,
+		"limits":  map[interface{}]interface{}{"cpu": 2},
+		"labels":  map[interface{}]interface{}{"team": "paasta"},
+	}
+	errorIf(test, !reflect.DeepEqual(expected, val), "expected %+v, got %+v", expected, val)
+}
+
+func TestDeepMerge(test *testing.T) {
+	dst := map[string]interface{}{
+		"a": map[string]interface{}{"b": 1, "c": 2},
+		"d": "e",
+	}
+	src := map[interface{}]interface{}{
+		"a": map[interface{}]interface{}{"c": 3, "f": 4},
+		"d": "e",
+	}
+	conflicts := []string{}
+	actual := deepMerge(dst, src, "key", &conflicts)
+	expected := map[string]interface{}{
+		"a": map[string]interface{}{"b": 1, "c": 3, "f": 4},
+		"d": "e",
+	}
+	errorIf(test, !reflect.DeepEqual(expected, actual), "expected %+v, got %+v", expected, actual)
+	errorIf(test, !reflect.DeepEqual([]string{"key.a.c"}, conflicts), "unexpected conflicts %v", conflicts)
+	errorUnexpected(test, 1, dst["a"].(map[string]interface{})["b"])
+	errorUnexpected(test, 2, dst["a"].(map[string]interface{})["c"])
+}
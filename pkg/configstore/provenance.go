@@ -0,0 +1,61 @@
+package configstore
+
+import (
+	"path"
+	"strings"
+)
+
+// Provenance describes where the value of a key was loaded from
+type Provenance struct {
+	// File is the path of the file the current value was loaded from
+	File string
+	// Format of `File`, e.g. `json` or `yaml`
+	Format string
+	// AlsoDefinedIn lists other loaded files defining the same key, whose
+	// values were overridden
+	AlsoDefinedIn []string
+}
+
+func formatOf(filepath string) string {
+	return strings.TrimPrefix(path.Ext(filepath), ".")
+}
+
+// recordProvenance marks `key` as loaded from `filepath`, files it was loaded
+// from before are kept in `AlsoDefinedIn`, must be called with s locked
+func (s *Store) recordProvenance(key, filepath string) {
+	if s.provenance == nil {
+		s.provenance = map[string]*Provenance{}
+	}
+	prov := &Provenance{File: filepath, Format: formatOf(filepath)}
+	if old, ok := s.provenance[key]; ok {
+		for _, file := range append(old.AlsoDefinedIn, old.File) {
+			if file != filepath && !contains(prov.AlsoDefinedIn, file) {
+				prov.AlsoDefinedIn = append(prov.AlsoDefinedIn, file)
+			}
+		}
+	}
+	s.provenance[key] = prov
+}
+
+// Where returns provenance of the value cached for `key`, it doesn't load
+// anything from disk
+func (s *Store) Where(key string) (Provenance, bool) {
+	s.Lock()
+	defer s.Unlock()
+	prov, ok := s.provenance[key]
+	if !ok {
+		return Provenance{}, false
+	}
+	res := *prov
+	res.AlsoDefinedIn = append([]string(nil), prov.AlsoDefinedIn...)
+	return res, true
+}
+
+func contains(list []string, item string) bool {
+	for _, el := range list {
+		if el == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package configstore
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStore_Where(test *testing.T) {
+	dir := test.TempDir()
+	start := time.Now().Add(-time.Hour)
+	writeConfig(test, path.Join(dir, "a.json"), `{"shared": "a", "only_a": 1}`, start)
+	writeConfig(test, path.Join(dir, "b.yaml"), "shared: b\nonly_b: 2\n", start)
+	writeConfig(test, path.Join(dir, "c.json"), `{"shared": "c"}`, start)
+	s := NewStore(dir, nil)
+
+	_, ok := s.Where("shared")
+	errorIf(test, ok, "provenance known before loading")
+
+	logs := &bytes.Buffer{}
+	log.SetOutput(logs)
+	defer log.SetOutput(os.Stderr)
+
+	val, ok, err := s.Get("shared")
+	errorIf(test, err != nil || !ok, "failed to get shared: %v", err)
+	errorUnexpected(test, "c", val)
+
+	prov, ok := s.Where("shared")
+	errorIf(test, !ok, "provenance of shared not found")
+	expected := Provenance{
+		File:          path.Join(dir, "c.json"),
+		Format:        "json",
+		AlsoDefinedIn: []string{path.Join(dir, "a.json"), path.Join(dir, "b.yaml")},
+	}
+	errorIf(test, !reflect.DeepEqual(expected, prov), "expected %+v, got %+v", expected, prov)
+
+	prov, _ = s.Where("only_b")
+	expected = Provenance{File: path.Join(dir, "b.yaml"), Format: "yaml"}
+	errorIf(test, !reflect.DeepEqual(expected, prov), "expected %+v, got %+v", expected, prov)
+
+	conflicts := strings.Count(logs.String(), "key shared is defined in both")
+	errorIf(test, conflicts != 2, "expected 2 conflicts logged, got %d in %q", conflicts, logs.String())
+
+	// overridden file dropping the key doesn't remove the winning value
+	writeConfig(test, path.Join(dir, "a.json"), `{"only_a": 1}`, start.Add(time.Minute))
+	changed, err := s.Refresh()
+	errorIf(test, err != nil, "refresh failed: %v", err)
+	errorIf(test, len(changed) != 0, "unexpected changes: %v", changed)
+	prov, _ = s.Where("shared")
+	errorIf(test, !reflect.DeepEqual([]string{path.Join(dir, "b.yaml")}, prov.AlsoDefinedIn), "unexpected provenance %+v", prov)
+
+	s.Invalidate("shared")
+	_, ok = s.Where("shared")
+	errorIf(test, ok, "provenance of invalidated key found")
+}
@@ -0,0 +1,66 @@
+package configstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v2"
+)
+
+// normalize returns a copy of `value` with dictionaries decoded from YAML
+// converted to map[string]interface{}, so that it can be encoded as JSON
+func normalize(value interface{}) interface{} {
+	if m, ok := toStringMap(value); ok {
+		res := make(map[string]interface{}, len(m))
+		for key, val := range m {
+			res[key] = normalize(val)
+		}
+		return res
+	}
+	if list, ok := value.([]interface{}); ok {
+		res := make([]interface{}, len(list))
+		for idx, val := range list {
+			res[idx] = normalize(val)
+		}
+		return res
+	}
+	return value
+}
+
+// Snapshot returns a copy of all values currently cached in the store, it
+// doesn't load anything from disk. With `s.Interpolator`, placeholders are
+// resolved unless it redacts them; values which fail to resolve are kept as
+// they are.
+func (s *Store) Snapshot() map[string]interface{} {
+	res := map[string]interface{}{}
+	s.Data.Range(func(key, val interface{}) bool {
+		if s.Interpolator != nil {
+			if s.Interpolator.Redact {
+				val = s.Interpolator.redact(val)
+			} else if resolved, err := s.Interpolator.Resolve(val); err == nil {
+				val = resolved
+			}
+		}
+		res[fmt.Sprint(key)] = normalize(val)
+		return true
+	})
+	return res
+}
+
+// WriteSnapshot writes `Snapshot` to `w` in `format`, `json` or `yaml`
+func (s *Store) WriteSnapshot(w io.Writer, format string) error {
+	snapshot := s.Snapshot()
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(snapshot)
+	case "yaml":
+		encoder := yaml.NewEncoder(w)
+		defer encoder.Close()
+		return encoder.Encode(snapshot)
+	default:
+		return fmt.Errorf("unknown snapshot format: %v", format)
+	}
+}
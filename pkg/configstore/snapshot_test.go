@@ -0,0 +1,113 @@
+package configstore
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestStore_Snapshot(test *testing.T) {
+	fsys := fstest.MapFS{
+		"paasta/registry.json": {Data: []byte(`{"docker_registry": "docker.yelp.com"}`)},
+		"paasta/volumes.yaml": {Data: []byte(`
+volumes:
+  - hostPath: /nail/etc
+    mode: RO
+`)},
+		"paasta/other.yml": {Data: []byte("other: {nested: true}\n")},
+	}
+	s := NewStoreFromFS(fsys, "paasta", map[string]string{"docker_registry": "registry"})
+
+	registry, err := Get[string](s, "docker_registry")
+	errorIf(test, err != nil, "failed to get docker_registry: %v", err)
+	errorUnexpected(test, "docker.yelp.com", registry)
+	errorUnexpected(test, 1, len(s.Snapshot()))
+
+	// loads all files
+	_, ok, err := s.Get("missing")
+	errorIf(test, ok || err != nil, "unexpected result for missing: %v", err)
+	expected := map[string]interface{}{
+		"docker_registry": "docker.yelp.com",
+		"volumes": []interface{}{
+			map[string]interface{}{"hostPath": "/nail/etc", "mode": "RO"},
+		},
+		"other": map[string]interface{}{"nested": true},
+	}
+	errorIf(test, !reflect.DeepEqual(expected, s.Snapshot()), "unexpected snapshot: %#v", s.Snapshot())
+
+	out := &bytes.Buffer{}
+	err = s.WriteSnapshot(out, "json")
+	errorIf(test, err != nil, "failed to write json: %v", err)
+	errorIf(test, !bytes.Contains(out.Bytes(), []byte(`"nested": true`)), "unexpected json: %s", out)
+
+	out.Reset()
+	err = s.WriteSnapshot(out, "yaml")
+	errorIf(test, err != nil, "failed to write yaml: %v", err)
+	errorIf(test, !bytes.Contains(out.Bytes(), []byte("docker_registry: docker.yelp.com\n")), "unexpected yaml: %s", out)
+
+	errorIf(test, s.WriteSnapshot(out, "xml") == nil, "expected error for unknown format")
+}
+
+func TestNewStoreFromMap(test *testing.T) {
+	s := NewStoreFromMap(map[string]interface{}{"docker_registry": "docker.yelp.com"})
+	registry, err := Get[string](s, "docker_registry")
+	errorIf(test, err != nil, "failed to get docker_registry: %v", err)
+	errorUnexpected(test, "docker.yelp.com", registry)
+
+	_, ok, err := s.Get("missing")
+	errorIf(test, ok || err != nil, "unexpected result for missing: %v", err)
+	errorIf(
+		test, !reflect.DeepEqual(map[string]interface{}{"docker_registry": "docker.yelp.com"}, s.Snapshot()),
+		"unexpected snapshot: %#v", s.Snapshot(),
+	)
+}
+
+func TestNewStoreFromMap_Load(test *testing.T) {
+	type deployGroup struct {
+		DockerImage string `mapstructure:"docker_image"`
+		GitSHA      string `mapstructure:"git_sha"`
+	}
+	s := NewStoreFromMap(map[string]interface{}{
+		"v2": map[string]interface{}{
+			"deployments": map[string]interface{}{
+				"dev.every": map[string]interface{}{
+					"docker_image": "busybox:latest",
+					"git_sha":      "abc123",
+				},
+			},
+		},
+	})
+
+	conf := struct {
+		Deployments map[string]deployGroup `mapstructure:"deployments"`
+	}{}
+	ok, err := s.Load("v2", &conf)
+	errorIf(test, !ok || err != nil, "failed to load v2: %v", err)
+	errorUnexpected(test, deployGroup{DockerImage: "busybox:latest", GitSHA: "abc123"}, conf.Deployments["dev.every"])
+
+	// missing keys never go to disk, even with hints
+	s.AddHint("v1", "deployments")
+	v1, err := GetOr[map[string]deployGroup](s, "v1", nil)
+	errorIf(test, v1 != nil || err != nil, "unexpected result for v1: %v %v", v1, err)
+}
+
+func TestNewStoreFromMap_Miss(test *testing.T) {
+	s := NewStoreFromMap(map[string]interface{}{"docker_registry": "docker.yelp.com"})
+	listed := 0
+	s.ListFiles = func(string) ([]string, error) {
+		listed++
+		return []string{}, nil
+	}
+
+	logs := &bytes.Buffer{}
+	log.SetOutput(logs)
+	defer log.SetOutput(os.Stderr)
+
+	_, ok, err := s.Get("missing")
+	errorIf(test, ok || err != nil, "unexpected result for missing: %v", err)
+	errorIf(test, listed != 0, "unexpected listing of files for missing")
+	errorIf(test, logs.Len() != 0, "unexpected logs: %s", logs)
+}
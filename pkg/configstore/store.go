@@ -6,10 +6,13 @@ package configstore
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"os"
 	"path"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/mitchellh/mapstructure"
@@ -22,7 +25,7 @@ import (
 // `Store` object will mimic config loading from original paasta-tools, which
 // works as following for `store.Get("foo")` call:
 //
-//  1. look on disk for file `foo.json` or `foo.yaml`
+//  1. look on disk for file `foo.json`, `foo.yaml` or `foo.yml`
 //  2. if file exists, load it and fetch `foo` key from top-level dictionary
 //  3. if file is missing, load all `.json` or `.yaml` files and merge them
 //     into single dictionary and look for `foo` key in there
@@ -30,15 +33,34 @@ import (
 // To avoid eagerly loading all existing configuration files, `Store` object
 // accepts optional `hints` dictionary, with mapping from keys to file paths,
 // where to look for those keys. If requested key is missing from hints, it will
-// trigger eager loading as per default functionality.
+// trigger eager loading as per default functionality. Hints may also point at
+// directories, e.g. `foo.d`, or glob patterns, e.g. `foo.d/*.yaml`, whose files
+// are loaded in lexical order and merged like any other files.
+//
+// With `UseIndex`, which is off by default, keys missing from hints are looked
+// up in an index mapping keys to files before loading everything. The index is
+// read from `IndexFileName` in `Dir` or from `IndexCacheDir`, and ignored when
+// files changed since it was built. After loading all files, the index is
+// rebuilt and persisted in `IndexCacheDir`, if set.
 //
 // There are two ways to get config data, via `Get` or `Load` methods. `Get`
 // will parse the config value and return it as `interface{}` type, while `Load`
 // will accept destination pointer and use `mapstructure.Decode` to destructure
 // the value.
 //
-// TODO: since `Store` is meant to be a long-lived object we need to keep track
-// of updated configs and a possibility to manually reset the cache.
+// Since `Store` is meant to be a long-lived object, cached values can be
+// dropped with `Reset` or `Invalidate`, and files which were loaded can be
+// checked for updates with `Refresh` or periodically with `Watch`. Callbacks
+// registered with `Subscribe` are called for every key changed by a reload.
+//
+// When loading all files, they are loaded in lexical order of their names,
+// same as paasta-tools does. When several files define the same key, the file
+// loaded last wins and a warning is logged, unless `DeepMerge` is set, in
+// which case dictionaries are merged recursively and only conflicting leaf
+// values are replaced. `Where` tells which file a cached value came from.
+//
+// With `Interpolator` set, `${ENV:NAME}` and `${FILE:/path}` placeholders in
+// string values are resolved by `Get`, cached values keep the placeholders.
 type Store struct {
 	Data  *sync.Map
 	Dir   string
@@ -48,6 +70,29 @@ type Store struct {
 	ListFiles  func(string) ([]string, error)
 	ParseFile  func(string, interface{}) error
 	FileExists func(string) (bool, error)
+	StatFile   func(string) (os.FileInfo, error)
+	ReadFile   func(string) ([]byte, error)
+
+	// DeepMerge makes dictionaries defined in several files merge
+	// recursively instead of being replaced
+	DeepMerge bool
+
+	// Interpolator resolves placeholders in values returned by `Get`, values
+	// are returned as they are when it's nil
+	Interpolator *Interpolator
+
+	// UseIndex enables looking up keys missing from hints in an index,
+	// built indexes are persisted in IndexCacheDir unless it's empty
+	UseIndex      bool
+	IndexCacheDir string
+
+	index       *Index
+	files       map[string]*loadedFile
+	subscribers []func(string)
+	provenance  map[string]*Provenance
+	validators  map[string][]Validator
+	// inMemory stores never look for keys missing from Data
+	inMemory bool
 }
 
 func listFiles(dirname string) ([]string, error) {
@@ -66,16 +111,20 @@ func listFiles(dirname string) ([]string, error) {
 
 func parseFile(filepath string, value interface{}) error {
 	reader, err := os.Open(filepath)
-	defer reader.Close()
 	if err != nil {
 		return fmt.Errorf("Failed to open %s: %v", filepath, err)
 	}
+	defer reader.Close()
 
-	ext := path.Ext(filepath)
+	return decode(reader, path.Ext(filepath), value)
+}
+
+// decode parses config from `reader` according to file extension `ext`
+func decode(reader io.Reader, ext string, value interface{}) error {
 	switch ext {
 	case ".json":
 		return json.NewDecoder(reader).Decode(value)
-	case ".yaml":
+	case ".yaml", ".yml":
 		return yaml.NewDecoder(reader).Decode(value)
 	default:
 		return fmt.Errorf("unknown extension: %v", ext)
@@ -108,12 +157,17 @@ func NewStore(dir string, hints map[string]string) *Store {
 		ListFiles:  listFiles,
 		ParseFile:  parseFile,
 		FileExists: fileExists,
+		StatFile:   os.Stat,
+		ReadFile:   os.ReadFile,
 	}
 }
 
 // Decode `path` contents using `json`, lock the store mutex, merge loaded data
-// into s.Data, unlock the mutex
+// into s.Data, remember the file for `Refresh`, unlock the mutex
 func (s *Store) loadPath(path string) error {
+	// stat before parsing, so that changes made while parsing aren't missed
+	file, statErr := s.stat(path)
+
 	value := map[string]interface{}{}
 	err := s.ParseFile(path, &value)
 	if err != nil {
@@ -123,17 +177,33 @@ func (s *Store) loadPath(path string) error {
 	s.Lock()
 	defer s.Unlock()
 	for key, val := range value {
+		if old, ok := s.Data.Load(key); ok {
+			conflicts := []string{}
+			val = s.merge(old, val, key, &conflicts)
+			if prov, ok := s.provenance[key]; ok && prov.File != path {
+				for _, conflict := range conflicts {
+					log.Printf(
+						"WARN: key %s is defined in both %s and %s, using value from %s",
+						conflict, prov.File, path, path,
+					)
+				}
+			}
+		}
 		s.Data.Store(key, val)
+		s.recordProvenance(key, path)
+	}
+	if statErr == nil {
+		s.track(path, file, value)
 	}
 
 	return nil
 }
 
-// Walk `s.dir` and `s.loadPath` all the files
+// Walk `s.dir` and `s.loadPath` all the files but the index in lexical order
 func (s *Store) loadAll() error {
-	files, err := s.ListFiles(s.Dir)
+	files, err := s.listConfigFiles()
 	if err != nil {
-		return fmt.Errorf("Failed to list %s: %v", s.Dir, err)
+		return err
 	}
 
 	for _, file := range files {
@@ -146,10 +216,15 @@ func (s *Store) loadAll() error {
 	return nil
 }
 
-var extensions = []string{"json", "yaml"}
+var extensions = []string{"json", "yaml", "yml"}
 
-// Look for `file`.json or `file`.yaml, if not found try loading all files and
-// print a warning about hints
+// supported tells whether `file` has one of the config extensions
+func supported(file string) bool {
+	return contains(extensions, strings.TrimPrefix(path.Ext(file), "."))
+}
+
+// Look for `file`.json, `file`.yaml or `file`.yml, then for a `file`
+// directory or glob pattern, whose files are loaded in lexical order
 func (s *Store) load(file string) error {
 	for _, ext := range extensions {
 		path := path.Join(s.Dir, fmt.Sprintf("%s.%s", file, ext))
@@ -166,15 +241,90 @@ func (s *Store) load(file string) error {
 		}
 	}
 
+	fragments, err := s.fragments(file)
+	if err != nil {
+		return err
+	}
+	for _, fragment := range fragments {
+		err = s.loadPath(fragment)
+		if err != nil {
+			return fmt.Errorf("Failed to load %s: %v", fragment, err)
+		}
+	}
 	return nil
 }
 
+func hasMeta(pattern string) bool {
+	return strings.ContainsAny(pattern, `*?[\`)
+}
+
+func (s *Store) isDir(filepath string) bool {
+	statFile := s.StatFile
+	if statFile == nil {
+		statFile = os.Stat
+	}
+	info, err := statFile(filepath)
+	return err == nil && info.IsDir()
+}
+
+// fragments returns paths of config files in `file` directory, e.g.
+// `foo.d`, or matching `file` glob pattern, e.g. `foo.d/*.yaml`, in lexical
+// order. Patterns are only allowed in the last path element.
+func (s *Store) fragments(file string) ([]string, error) {
+	dir, pattern := path.Join(s.Dir, file), "*"
+	if hasMeta(file) {
+		dir, pattern = path.Split(path.Join(s.Dir, file))
+		if hasMeta(dir) {
+			return nil, fmt.Errorf("pattern %s is only allowed in the last path element", file)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("Invalid pattern %s: %v", file, err)
+		}
+	} else if !s.isDir(dir) {
+		return nil, nil
+	}
+
+	files, err := s.ListFiles(dir)
+	if err != nil {
+		if hasMeta(file) && !s.isDir(dir) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("Failed to list %s: %v", dir, err)
+	}
+	sort.Strings(files)
+	res := []string{}
+	for _, name := range files {
+		matched, _ := path.Match(pattern, name)
+		filepath := path.Join(dir, name)
+		if matched && supported(name) && !s.isDir(filepath) {
+			res = append(res, filepath)
+		}
+	}
+	return res, nil
+}
+
 // Get returns value for given `key`. If not found in `s.data`, call
-// `s.load` function with `file` from `s.hints` or `key` itself.
+// `s.load` function with `file` from `s.hints` or `key` itself. Placeholders
+// in string values are resolved with `s.Interpolator`, if set.
 func (s *Store) Get(key string) (interface{}, bool, error) {
+	val, ok, err := s.get(key)
+	if err != nil || !ok || s.Interpolator == nil {
+		return val, ok, err
+	}
+	val, err = s.Interpolator.Resolve(val)
+	if err != nil {
+		return nil, false, fmt.Errorf("Failed to interpolate %s: %w", key, err)
+	}
+	return val, true, nil
+}
+
+func (s *Store) get(key string) (interface{}, bool, error) {
 	if val, ok := s.Data.Load(key); ok {
 		return val, ok, nil
 	}
+	if s.inMemory {
+		return nil, false, nil
+	}
 
 	var file string
 	var fromHint bool
@@ -191,25 +341,32 @@ func (s *Store) Get(key string) (interface{}, bool, error) {
 	}
 
 	val, ok := s.Data.Load(key)
-	if !ok {
-		if !fromHint {
-			log.Printf(
-				"WARN: loading all configs, consider adding some hints in %s",
-				path.Join(s.Dir, file),
-			)
-			err := s.loadAll()
+	if !ok && !fromHint {
+		if idx := s.freshIndex(); idx != nil {
+			err := s.loadIndexed(idx, key)
 			if err != nil {
-				return nil, false, fmt.Errorf("failed to load all configs: %v", err)
+				return nil, false, fmt.Errorf("failed to load indexed configs: %v", err)
 			}
 			val, ok = s.Data.Load(key)
+			return val, ok, nil
 		}
+		log.Printf(
+			"WARN: loading all configs, consider adding some hints in %s",
+			path.Join(s.Dir, file),
+		)
+		err := s.loadAll()
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to load all configs: %v", err)
+		}
+		s.updateIndex()
+		val, ok = s.Data.Load(key)
 	}
 
 	return val, ok, nil
 }
 
 // Load uses mapstructure.Decode to parse result of a Get into provided
-// destination value
+// destination value, and runs validators registered for `key`
 func (s *Store) Load(key string, dst interface{}) (bool, error) {
 	val, ok, err := s.Get(key)
 	if err != nil {
@@ -218,7 +375,10 @@ func (s *Store) Load(key string, dst interface{}) (bool, error) {
 	if !ok {
 		return false, nil
 	}
-	return true, mapstructure.Decode(val, dst)
+	if err := mapstructure.Decode(val, dst); err != nil {
+		return true, err
+	}
+	return true, s.validate(key, dst)
 }
 
 // AddHint inserts a new hint to find keys among config files
@@ -0,0 +1,42 @@
+package configstore
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrKeyNotFound is returned by `Get` and `MustGet` when no config defines the
+// requested key, use `errors.Is` to check for it
+var ErrKeyNotFound = errors.New("config key not found")
+
+// Get loads `key` from `store` into a new value of type T, errors wrap
+// `ErrKeyNotFound` when the key is missing
+func Get[T any](store Reader, key string) (T, error) {
+	var val T
+	ok, err := store.Load(key, &val)
+	if err != nil {
+		return val, fmt.Errorf("Failed to load %s: %w", key, err)
+	}
+	if !ok {
+		return val, fmt.Errorf("%w: %s", ErrKeyNotFound, key)
+	}
+	return val, nil
+}
+
+// GetOr works like `Get`, but returns `def` when the key is missing
+func GetOr[T any](store Reader, key string, def T) (T, error) {
+	val, err := Get[T](store, key)
+	if errors.Is(err, ErrKeyNotFound) {
+		return def, nil
+	}
+	return val, err
+}
+
+// MustGet works like `Get`, but panics on any error
+func MustGet[T any](store Reader, key string) T {
+	val, err := Get[T](store, key)
+	if err != nil {
+		panic(err)
+	}
+	return val
+}
@@ -0,0 +1,53 @@
+package configstore
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGet(test *testing.T) {
+	s := storeWith(map[string]interface{}{
+		"registry": "docker.yelp.com",
+		"limits":   map[string]interface{}{"cpu": 2},
+	})
+
+	registry, err := Get[string](s, "registry")
+	errorIf(test, err != nil, "failed to get registry: %v", err)
+	errorUnexpected(test, "docker.yelp.com", registry)
+
+	limits, err := Get[map[string]int](s, "limits")
+	errorIf(test, err != nil, "failed to get limits: %v", err)
+	errorUnexpected(test, 2, limits["cpu"])
+
+	_, err = Get[string](s, "missing")
+	errorIf(test, !errors.Is(err, ErrKeyNotFound), "expected ErrKeyNotFound, got %v", err)
+
+	_, err = Get[int](s, "registry")
+	errorIf(test, err == nil || errors.Is(err, ErrKeyNotFound), "expected decode error, got %v", err)
+}
+
+func TestGetOr(test *testing.T) {
+	s := storeWith(map[string]interface{}{"registry": "docker.yelp.com"})
+
+	registry, err := GetOr(s, "registry", "default")
+	errorIf(test, err != nil, "failed to get registry: %v", err)
+	errorUnexpected(test, "docker.yelp.com", registry)
+
+	registry, err = GetOr(s, "missing", "default")
+	errorIf(test, err != nil, "failed to get missing: %v", err)
+	errorUnexpected(test, "default", registry)
+
+	_, err = GetOr(s, "registry", 0)
+	errorIf(test, err == nil, "expected decode error")
+}
+
+func TestMustGet(test *testing.T) {
+	s := storeWith(map[string]interface{}{"registry": "docker.yelp.com"})
+	errorUnexpected(test, "docker.yelp.com", MustGet[string](s, "registry"))
+
+	defer func() {
+		err, _ := recover().(error)
+		errorIf(test, !errors.Is(err, ErrKeyNotFound), "expected ErrKeyNotFound panic, got %v", err)
+	}()
+	MustGet[string](s, "missing")
+}
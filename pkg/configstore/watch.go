@@ -0,0 +1,216 @@
+package configstore
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// loadedFile is the state of a file at the time it was loaded into the store
+type loadedFile struct {
+	modTime time.Time
+	size    int64
+	values  map[string]interface{}
+}
+
+func (f *loadedFile) changed(other *loadedFile) bool {
+	return !f.modTime.Equal(other.modTime) || f.size != other.size
+}
+
+func (s *Store) stat(path string) (*loadedFile, error) {
+	statFile := s.StatFile
+	if statFile == nil {
+		statFile = os.Stat
+	}
+	info, err := statFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &loadedFile{modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// track remembers values loaded from `path`, must be called with s locked
+func (s *Store) track(path string, file *loadedFile, value map[string]interface{}) {
+	if s.files == nil {
+		s.files = map[string]*loadedFile{}
+	}
+	file.values = value
+	s.files[path] = file
+}
+
+// Reset drops all cached values, they will be loaded from disk again on the
+// next `Get`
+func (s *Store) Reset() {
+	s.Lock()
+	defer s.Unlock()
+	s.Data.Range(func(key, _ interface{}) bool {
+		s.Data.Delete(key)
+		return true
+	})
+	s.files = nil
+	s.provenance = nil
+}
+
+// Invalidate drops cached value for `key`, it will be loaded from disk again
+// on the next `Get`
+func (s *Store) Invalidate(key string) {
+	s.Lock()
+	defer s.Unlock()
+	s.Data.Delete(key)
+	delete(s.provenance, key)
+}
+
+// Subscribe registers a callback called with every key changed by `Refresh`
+func (s *Store) Subscribe(callback func(key string)) {
+	s.Lock()
+	defer s.Unlock()
+	s.subscribers = append(s.subscribers, callback)
+}
+
+// replaceFile swaps values loaded from `path` with `value`, or drops them when
+// file is nil. Affected keys are merged again from all loaded files in lexical
+// order. Returns keys which changed, must be called with s locked.
+func (s *Store) replaceFile(path string, file *loadedFile, value map[string]interface{}) []string {
+	affected := map[string]bool{}
+	if old, ok := s.files[path]; ok {
+		for key := range old.values {
+			affected[key] = true
+		}
+	}
+	for key := range value {
+		affected[key] = true
+	}
+	if file != nil {
+		s.track(path, file, value)
+	} else {
+		delete(s.files, path)
+	}
+
+	paths := make([]string, 0, len(s.files))
+	for filepath := range s.files {
+		paths = append(paths, filepath)
+	}
+	sort.Strings(paths)
+
+	changed := []string{}
+	for key := range affected {
+		var merged interface{}
+		var prov *Provenance
+		for _, filepath := range paths {
+			val, ok := s.files[filepath].values[key]
+			if !ok {
+				continue
+			}
+			if prov == nil {
+				merged = val
+				prov = &Provenance{}
+			} else {
+				merged = s.merge(merged, val, key, nil)
+				prov.AlsoDefinedIn = append(prov.AlsoDefinedIn, prov.File)
+			}
+			prov.File = filepath
+			prov.Format = formatOf(filepath)
+		}
+
+		old, hadOld := s.Data.Load(key)
+		if prov == nil {
+			s.Data.Delete(key)
+			delete(s.provenance, key)
+			if hadOld {
+				changed = append(changed, key)
+			}
+			continue
+		}
+		if !hadOld || !reflect.DeepEqual(old, merged) {
+			changed = append(changed, key)
+		}
+		s.Data.Store(key, merged)
+		if s.provenance == nil {
+			s.provenance = map[string]*Provenance{}
+		}
+		s.provenance[key] = prov
+	}
+	return changed
+}
+
+// Refresh reloads files which were modified or removed since they were loaded
+// and returns keys whose values changed. Files which fail to parse keep their
+// previously loaded values.
+func (s *Store) Refresh() ([]string, error) {
+	s.Lock()
+	files := make(map[string]*loadedFile, len(s.files))
+	paths := make([]string, 0, len(s.files))
+	for path, file := range s.files {
+		files[path] = file
+		paths = append(paths, path)
+	}
+	s.Unlock()
+	sort.Strings(paths)
+
+	changedSet := map[string]bool{}
+	errors := []string{}
+	for _, path := range paths {
+		file, err := s.stat(path)
+		if err != nil && !os.IsNotExist(err) {
+			errors = append(errors, fmt.Sprintf("Failed to stat %s: %v", path, err))
+			continue
+		}
+
+		value := map[string]interface{}{}
+		if file != nil {
+			if !file.changed(files[path]) {
+				continue
+			}
+			if err := s.ParseFile(path, &value); err != nil {
+				errors = append(errors, fmt.Sprintf("Failed to parse %s: %v", path, err))
+				continue
+			}
+		}
+
+		s.Lock()
+		for _, key := range s.replaceFile(path, file, value) {
+			changedSet[key] = true
+		}
+		s.Unlock()
+	}
+	changed := make([]string, 0, len(changedSet))
+	for key := range changedSet {
+		changed = append(changed, key)
+	}
+	sort.Strings(changed)
+
+	s.Lock()
+	subscribers := append([]func(string){}, s.subscribers...)
+	s.Unlock()
+	for _, key := range changed {
+		for _, callback := range subscribers {
+			callback(key)
+		}
+	}
+
+	if len(errors) > 0 {
+		return changed, fmt.Errorf("%s", strings.Join(errors, ", "))
+	}
+	return changed, nil
+}
+
+// Watch calls `Refresh` every `interval` until ctx is done, errors are logged
+func (s *Store) Watch(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if _, err := s.Refresh(); err != nil {
+				log.Printf("WARN: failed to refresh configs in %s: %v", s.Dir, err)
+			}
+		}
+	}
+}
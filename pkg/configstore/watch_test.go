@@ -0,0 +1,114 @@
+package configstore
+
+import (
+	"context"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func writeConfig(test *testing.T, filepath, content string, mtime time.Time) {
+	if err := os.WriteFile(filepath, []byte(content), 0644); err != nil {
+		test.Fatal(err)
+	}
+	if err := os.Chtimes(filepath, mtime, mtime); err != nil {
+		test.Fatal(err)
+	}
+}
+
+func TestStore_ResetAndInvalidate(test *testing.T) {
+	dir := test.TempDir()
+	start := time.Now().Add(-time.Hour)
+	writeConfig(test, path.Join(dir, "one.json"), `{"one": 1, "two": 2}`, start)
+	s := NewStore(dir, nil)
+
+	val, ok, err := s.Get("one")
+	errorIf(test, err != nil || !ok, "failed to get one: %v", err)
+	errorUnexpected(test, float64(1), val)
+
+	writeConfig(test, path.Join(dir, "one.json"), `{"one": 10, "two": 20}`, start)
+	val, _, _ = s.Get("one")
+	errorUnexpected(test, float64(1), val)
+
+	s.Invalidate("one")
+	_, ok = s.Data.Load("one")
+	errorIf(test, ok, "one wasn't invalidated")
+	_, ok = s.Data.Load("two")
+	errorIf(test, !ok, "two was invalidated")
+	val, _, _ = s.Get("one")
+	errorUnexpected(test, float64(10), val)
+
+	s.Reset()
+	_, ok = s.Data.Load("two")
+	errorIf(test, ok, "two wasn't reset")
+	val, _, _ = s.Get("two")
+	errorUnexpected(test, float64(20), val)
+}
+
+func TestStore_Refresh(test *testing.T) {
+	dir := test.TempDir()
+	start := time.Now().Add(-time.Hour)
+	writeConfig(test, path.Join(dir, "one.json"), `{"one": 1, "gone": true, "same": "x"}`, start)
+	writeConfig(test, path.Join(dir, "two.yaml"), "two: 2\n", start)
+	s := NewStore(dir, map[string]string{"one": "one", "two": "two"})
+
+	notified := []string{}
+	s.Subscribe(func(key string) { notified = append(notified, key) })
+
+	s.Get("one")
+	s.Get("two")
+
+	changed, err := s.Refresh()
+	errorIf(test, err != nil, "refresh failed: %v", err)
+	errorIf(test, len(changed) != 0, "unexpected changes: %v", changed)
+
+	writeConfig(test, path.Join(dir, "one.json"), `{"one": 11, "new": [1], "same": "x"}`, start.Add(time.Minute))
+	os.Remove(path.Join(dir, "two.yaml"))
+
+	changed, err = s.Refresh()
+	errorIf(test, err != nil, "refresh failed: %v", err)
+	expected := []string{"gone", "new", "one", "two"}
+	errorIf(test, !reflect.DeepEqual(expected, changed), "expected changes %v, got %v", expected, changed)
+	errorIf(test, !reflect.DeepEqual(expected, notified), "expected notifications %v, got %v", expected, notified)
+
+	val, _ := s.Data.Load("one")
+	errorUnexpected(test, float64(11), val)
+	_, ok := s.Data.Load("gone")
+	errorIf(test, ok, "gone wasn't removed")
+	_, ok = s.Data.Load("two")
+	errorIf(test, ok, "two wasn't removed")
+
+	// broken file keeps old values
+	writeConfig(test, path.Join(dir, "one.json"), `{"one": `, start.Add(2*time.Minute))
+	_, err = s.Refresh()
+	errorIf(test, err == nil, "expected refresh to fail")
+	val, _ = s.Data.Load("one")
+	errorUnexpected(test, float64(11), val)
+}
+
+func TestStore_Watch(test *testing.T) {
+	dir := test.TempDir()
+	start := time.Now().Add(-time.Hour)
+	writeConfig(test, path.Join(dir, "one.json"), `{"one": 1}`, start)
+	s := NewStore(dir, nil)
+	s.Get("one")
+
+	notified := make(chan string, 1)
+	s.Subscribe(func(key string) { notified <- key })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Watch(ctx, time.Millisecond) }()
+
+	writeConfig(test, path.Join(dir, "one.json"), `{"one": 2}`, start.Add(time.Minute))
+	select {
+	case key := <-notified:
+		errorUnexpected(test, "one", key)
+	case <-time.After(5 * time.Second):
+		test.Fatalf("change wasn't noticed")
+	}
+	cancel()
+	errorUnexpected(test, context.Canceled, <-done)
+}
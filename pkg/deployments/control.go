@@ -0,0 +1,150 @@
+package deployments
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Yelp/paasta-tools-go/pkg/utils"
+)
+
+// Desired states of control groups
+const (
+	DesiredStateStart = "start"
+	DesiredStateStop  = "stop"
+)
+
+// Annotations paasta sets on kubernetes objects of an instance
+const (
+	DesiredStateAnnotation = "paasta.yelp.com/desired_state"
+	ForceBounceAnnotation  = "paasta.yelp.com/force_bounce"
+)
+
+// ForceBounceTimeFormat is the format of force bounce tokens, same as the
+// timestamps of paasta-tools start/stop git tags
+const ForceBounceTimeFormat = "20060102T150405"
+
+// diffContext is the number of context lines in diffs of control updates
+const diffContext = 3
+
+// ForceBounceToken returns a force bounce token for `t`
+func ForceBounceToken(t time.Time) string {
+	return t.UTC().Format(ForceBounceTimeFormat)
+}
+
+// ControlAnnotations returns the annotations of an instance with `control`
+func ControlAnnotations(control V2ControlGroup) map[string]string {
+	return map[string]string{
+		DesiredStateAnnotation: control.DesiredState,
+		ForceBounceAnnotation:  control.ForceBounce,
+	}
+}
+
+// ControlUpdate is the result of changing a control group: the updated
+// deployments and what changed
+type ControlUpdate struct {
+	Deployments  *Deployments
+	ControlGroup string
+	Old          V2ControlGroup
+	New          V2ControlGroup
+	// Diff is a unified diff of the control group in YAML, empty when nothing
+	// changed
+	Diff string
+}
+
+// Annotations returns the annotations of instances of the updated control
+// group
+func (update *ControlUpdate) Annotations() map[string]string {
+	return ControlAnnotations(update.New)
+}
+
+// Changed reports whether the update changed the control group
+func (update *ControlUpdate) Changed() bool {
+	return update.Old != update.New
+}
+
+// Copy returns a deep copy of deployments
+func (d *Deployments) Copy() *Deployments {
+	res := &Deployments{V1: nil, V2: V2DeploymentsConfig{}}
+	if d.V1 != nil {
+		res.V1 = make(map[string]V1Deployment, len(d.V1))
+		for key, val := range d.V1 {
+			res.V1[key] = val
+		}
+	}
+	if d.V2.Deployments != nil {
+		res.V2.Deployments = make(map[string]V2DeploymentGroup, len(d.V2.Deployments))
+		for key, val := range d.V2.Deployments {
+			res.V2.Deployments[key] = val
+		}
+	}
+	if d.V2.Controls != nil {
+		res.V2.Controls = make(map[string]V2ControlGroup, len(d.V2.Controls))
+		for key, val := range d.V2.Controls {
+			res.V2.Controls[key] = val
+		}
+	}
+	return res
+}
+
+// SetDesiredState returns deployments with the desired state of
+// `controlGroup` set to `DesiredStateStart` or `DesiredStateStop`. The force
+// bounce token is kept, `d` is not modified.
+func (d *Deployments) SetDesiredState(controlGroup, desiredState string) (*ControlUpdate, error) {
+	if desiredState != DesiredStateStart && desiredState != DesiredStateStop {
+		return nil, fmt.Errorf(
+			"Invalid desired state %q, expected %q or %q",
+			desiredState, DesiredStateStart, DesiredStateStop,
+		)
+	}
+	return d.updateControl(controlGroup, func(control *V2ControlGroup) {
+		control.DesiredState = desiredState
+	})
+}
+
+// ForceBounce returns deployments with a new force bounce token minted from
+// `now` for `controlGroup`, which makes paasta bounce its instances. `d` is
+// not modified.
+func (d *Deployments) ForceBounce(controlGroup string, now time.Time) (*ControlUpdate, error) {
+	return d.updateControl(controlGroup, func(control *V2ControlGroup) {
+		control.ForceBounce = ForceBounceToken(now)
+	})
+}
+
+// Restart returns deployments with `controlGroup` started and force bounced
+// at `now`, like `paasta restart` does. `d` is not modified.
+func (d *Deployments) Restart(controlGroup string, now time.Time) (*ControlUpdate, error) {
+	return d.updateControl(controlGroup, func(control *V2ControlGroup) {
+		control.DesiredState = DesiredStateStart
+		control.ForceBounce = ForceBounceToken(now)
+	})
+}
+
+func (d *Deployments) updateControl(
+	controlGroup string, update func(*V2ControlGroup),
+) (*ControlUpdate, error) {
+	old, ok := d.V2.Controls[controlGroup]
+	if !ok {
+		return nil, fmt.Errorf("Control group %s does not exist", controlGroup)
+	}
+	control := old
+	update(&control)
+	res := &ControlUpdate{
+		Deployments:  d.Copy(),
+		ControlGroup: controlGroup,
+		Old:          old,
+		New:          control,
+	}
+	res.Deployments.V2.Controls[controlGroup] = control
+	if res.Changed() {
+		diff, err := utils.GetYamlDiffForObjects(
+			map[string]V2ControlGroup{controlGroup: old},
+			map[string]V2ControlGroup{controlGroup: control},
+			diffContext,
+		)
+		if err != nil {
+			return nil, err
+		}
+		res.Diff = diff
+	}
+	return res, nil
+}
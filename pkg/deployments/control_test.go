@@ -0,0 +1,68 @@
+package deployments
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func controlTestDeployments() *Deployments {
+	d := NewDeployments()
+	d.SetDeployment("prod.everything", V2DeploymentGroup{
+		DockerImage: "services-fluffy:paasta-abc123",
+		GitSHA:      "abc123",
+	})
+	d.SetControl("fluffy", "norcal-prod", "main", V2ControlGroup{
+		DesiredState: DesiredStateStart,
+		ForceBounce:  "20200101T000000",
+	})
+	return d
+}
+
+func TestForceBounceToken(test *testing.T) {
+	now := time.Date(2021, 3, 4, 5, 6, 7, 0, time.FixedZone("PST", -8*3600))
+	assert.Equal(test, "20210304T130607", ForceBounceToken(now))
+}
+
+func TestSetDesiredState(test *testing.T) {
+	d := controlTestDeployments()
+	update, err := d.SetDesiredState("fluffy:norcal-prod.main", DesiredStateStop)
+	assert.NoError(test, err)
+	assert.True(test, update.Changed())
+	assert.Equal(test, V2ControlGroup{DesiredState: "stop", ForceBounce: "20200101T000000"}, update.New)
+	assert.Equal(test, update.New, update.Deployments.V2.Controls["fluffy:norcal-prod.main"])
+	assert.Equal(test, DesiredStateStart, d.V2.Controls["fluffy:norcal-prod.main"].DesiredState)
+	assert.Equal(test, map[string]string{
+		"paasta.yelp.com/desired_state": "stop",
+		"paasta.yelp.com/force_bounce":  "20200101T000000",
+	}, update.Annotations())
+	assert.True(test, strings.Contains(update.Diff, "-  desired_state: start\n+  desired_state: stop\n"), update.Diff)
+
+	update, err = update.Deployments.SetDesiredState("fluffy:norcal-prod.main", DesiredStateStop)
+	assert.NoError(test, err)
+	assert.False(test, update.Changed())
+	assert.Equal(test, "", update.Diff)
+
+	_, err = d.SetDesiredState("fluffy:norcal-prod.main", "restart")
+	assert.Error(test, err)
+	_, err = d.SetDesiredState("fluffy:norcal-prod.canary", DesiredStateStop)
+	assert.Error(test, err)
+}
+
+func TestRestart(test *testing.T) {
+	d := controlTestDeployments()
+	d.V2.Controls["fluffy:norcal-prod.main"] = V2ControlGroup{DesiredState: DesiredStateStop}
+	now := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	update, err := d.ForceBounce("fluffy:norcal-prod.main", now)
+	assert.NoError(test, err)
+	assert.Equal(test, V2ControlGroup{DesiredState: "stop", ForceBounce: "20210304T050607"}, update.New)
+
+	update, err = d.Restart("fluffy:norcal-prod.main", now)
+	assert.NoError(test, err)
+	assert.Equal(test, V2ControlGroup{DesiredState: "start", ForceBounce: "20210304T050607"}, update.New)
+	assert.Equal(test, d.V2.Deployments, update.Deployments.V2.Deployments)
+	assert.Equal(test, V2ControlGroup{DesiredState: "stop"}, d.V2.Controls["fluffy:norcal-prod.main"])
+}
@@ -15,19 +15,27 @@
 //	    }
 //	  }
 //	}
+//
+// Deployments can also be generated with `GenerateDeployments` and written
+// in the layout paasta-tools uses with `WriteDeployments`.
 package deployments
 
 import (
+	"errors"
 	"fmt"
 	"path"
 
 	"github.com/Yelp/paasta-tools-go/pkg/configstore"
+	"github.com/Yelp/paasta-tools-go/pkg/soaconfigs"
+	"github.com/Yelp/paasta-tools-go/pkg/systemconfig"
+	"github.com/mitchellh/mapstructure"
 )
 
 // V2DeploymentGroup ...
 type V2DeploymentGroup struct {
-	DockerImage string `json:"docker_image" mapstructure:"docker_image"`
-	GitSHA      string `json:"git_sha" mapstructure:"git_sha"`
+	DockerImage  string `json:"docker_image" mapstructure:"docker_image"`
+	GitSHA       string `json:"git_sha" mapstructure:"git_sha"`
+	ImageVersion string `json:"image_version" mapstructure:"image_version"`
 }
 
 // V2ControlGroup ...
@@ -42,9 +50,17 @@ type V2DeploymentsConfig struct {
 	Controls    map[string]V2ControlGroup    `json:"controls" mapstructure:"controls"`
 }
 
+// V1Deployment is a legacy deployment, keyed by `<service>:paasta-<branch>`
+type V1Deployment struct {
+	DockerImage  string `json:"docker_image" mapstructure:"docker_image"`
+	DesiredState string `json:"desired_state" mapstructure:"desired_state"`
+	ForceBounce  string `json:"force_bounce" mapstructure:"force_bounce"`
+}
+
 // Deployments ...
 type Deployments struct {
-	V2 V2DeploymentsConfig `json:"v2" mapstructure:"v2"`
+	V1 map[string]V1Deployment `json:"v1" mapstructure:"v1"`
+	V2 V2DeploymentsConfig     `json:"v2" mapstructure:"v2"`
 }
 
 // DockerRegistry ...
@@ -62,17 +78,22 @@ type DefaultImageProvider struct {
 	PaastaConfig  *configstore.Store
 }
 
+// DefaultServiceConfigRoot is where service configs with deployments are
+// checked out
+const DefaultServiceConfigRoot = soaconfigs.DefaultRoot
+
 // NewDefaultImageProviderForService ...
 func NewDefaultImageProviderForService(service string) *DefaultImageProvider {
-	serviceConfig := configstore.NewStore(
-		path.Join("/nail/etc/services", service),
-		map[string]string{"v2": "deployments"},
-	)
-	paastaConfig := configstore.NewStore("/etc/paasta", nil)
+	return NewImageProviderForService(service, DefaultServiceConfigRoot)
+}
+
+// NewImageProviderForService creates an image provider reading deployments
+// of `service` from `serviceConfigRoot` instead of `DefaultServiceConfigRoot`
+func NewImageProviderForService(service, serviceConfigRoot string) *DefaultImageProvider {
 	return &DefaultImageProvider{
 		Service:       service,
-		ServiceConfig: serviceConfig,
-		PaastaConfig:  paastaConfig,
+		ServiceConfig: serviceConfigStore(serviceConfigRoot, service),
+		PaastaConfig:  systemconfig.NewStore(systemconfig.DefaultDir),
 	}
 }
 
@@ -95,32 +116,22 @@ func (provider *DefaultImageProvider) DockerImageURLForDeployGroup(deploymentGro
 }
 
 func (provider *DefaultImageProvider) getDockerRegistry() (string, error) {
-	dockerRegistry := &DockerRegistry{Registry: ""}
-	ok, err := provider.PaastaConfig.Load("docker_registry", &dockerRegistry.Registry)
-	if !ok {
-		return "", fmt.Errorf("docker registry not found")
-	}
-	return dockerRegistry.Registry, err
+	return systemconfig.New(provider.PaastaConfig).DockerRegistry()
 }
 
 func (provider *DefaultImageProvider) getImageForDeployGroup(deploymentGroup string) (string, error) {
-	deployments := &Deployments{V2: V2DeploymentsConfig{}}
-	ok, err := provider.ServiceConfig.Load("v2", &deployments.V2)
+	deployments, err := deploymentsFromConfig(provider.ServiceConfig)
 	if err != nil {
 		return "", err
 	}
-	if !ok {
-		return "", fmt.Errorf("image for deploymentGroup %v not found", deploymentGroup)
-	}
-	deployment, ok := deployments.V2.Deployments[deploymentGroup]
-
+	info, ok := deployments.DeploymentInfo(provider.Service, deploymentGroup)
 	if !ok {
 		return "", fmt.Errorf(
-			"Deployment group %s not found in v2 deployments of %+v",
+			"Deployment group %s not found in deployments of %+v",
 			deploymentGroup, deployments,
 		)
 	}
-	return deployment.DockerImage, nil
+	return info.DockerImage, nil
 }
 
 // DeploymentAnnotations returns a map of annotations for the relevant service
@@ -128,10 +139,7 @@ func (provider *DefaultImageProvider) getImageForDeployGroup(deploymentGroup str
 func DeploymentAnnotations(
 	service, cluster, instance, deploymentGroup string,
 ) (map[string]string, error) {
-	configStore := configstore.NewStore(
-		fmt.Sprintf("/nail/etc/services/%s", service),
-		map[string]string{"v2": "deployments"},
-	)
+	configStore := serviceConfigStore(DefaultServiceConfigRoot, service)
 	deployments, err := deploymentsFromConfig(configStore)
 	if err != nil {
 		return nil, fmt.Errorf(
@@ -142,13 +150,44 @@ func DeploymentAnnotations(
 	return deploymentAnnotationsForControlGroup(deployments, controlGroup)
 }
 
+// deploymentsHints tell config stores of services where to find deployments
+var deploymentsHints = map[string]string{"v1": "deployments", "v2": "deployments"}
+
+// serviceConfigStore creates a config store for deployments of `service`
+func serviceConfigStore(serviceConfigRoot, service string) *configstore.Store {
+	return configstore.NewStore(path.Join(serviceConfigRoot, service), deploymentsHints)
+}
+
+// deploymentsFromConfig reads deployments of a service, the v2 section is
+// optional for legacy services which only have the v1 one
 func deploymentsFromConfig(cr *configstore.Store) (*Deployments, error) {
-	conf := &V2DeploymentsConfig{}
-	ok, err := cr.Load("v2", conf)
+	conf, v2Err := configstore.Get[V2DeploymentsConfig](cr, "v2")
+	if v2Err != nil && !errors.Is(v2Err, configstore.ErrKeyNotFound) {
+		return nil, v2Err
+	}
+	v1, err := loadedV1Deployments(cr)
+	if err != nil {
+		return nil, err
+	}
+	if v2Err != nil && len(v1) == 0 {
+		return nil, v2Err
+	}
+	return &Deployments{V1: v1, V2: conf}, nil
+}
+
+// loadedV1Deployments decodes the v1 section if it was loaded. Both sections
+// live in deployments.json, so it's loaded while looking up v2, and looking
+// it up again would only reload files when it's missing.
+func loadedV1Deployments(cr *configstore.Store) (map[string]V1Deployment, error) {
+	val, ok := cr.Data.Load("v1")
 	if !ok {
-		return nil, fmt.Errorf("deployments not found")
+		return nil, nil
+	}
+	v1 := map[string]V1Deployment{}
+	if err := mapstructure.Decode(val, &v1); err != nil {
+		return nil, fmt.Errorf("Failed to load v1: %v", err)
 	}
-	return &Deployments{V2: *conf}, err
+	return v1, nil
 }
 
 func makeControlGroup(service, instance, cluster string) string {
@@ -156,12 +195,9 @@ func makeControlGroup(service, instance, cluster string) string {
 }
 
 func deploymentAnnotationsForControlGroup(ds *Deployments, cg string) (map[string]string, error) {
-	annotations := map[string]string{}
 	control, ok := ds.V2.Controls[cg]
 	if !ok {
 		return nil, fmt.Errorf("Control group %s does not exist", cg)
 	}
-	annotations["paasta.yelp.com/desired_state"] = control.DesiredState
-	annotations["paasta.yelp.com/force_bounce"] = control.ForceBounce
-	return annotations, nil
+	return ControlAnnotations(control), nil
 }
@@ -0,0 +1,281 @@
+package deployments
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Yelp/paasta-tools-go/pkg/utils"
+)
+
+// ChangeType is how an entry changed between two deployments
+type ChangeType string
+
+// Types of changes
+const (
+	ChangeAdded    ChangeType = "added"
+	ChangeRemoved  ChangeType = "removed"
+	ChangeModified ChangeType = "modified"
+)
+
+// DeploymentChange is a change of the deployment of a deploy group, `Old`
+// is nil for added groups and `New` for removed ones
+type DeploymentChange struct {
+	DeployGroup string             `json:"deploy_group"`
+	Type        ChangeType         `json:"type"`
+	Old         *V2DeploymentGroup `json:"old"`
+	New         *V2DeploymentGroup `json:"new"`
+}
+
+// GitSHAChanged reports whether the deploy group moved to another commit
+func (change DeploymentChange) GitSHAChanged() bool {
+	return change.Old != nil && change.New != nil && change.Old.GitSHA != change.New.GitSHA
+}
+
+// ControlGroupChange is a change of the control entry of an instance, `Old`
+// is nil for added control groups and `New` for removed ones
+type ControlGroupChange struct {
+	ControlGroup string          `json:"control_group"`
+	Type         ChangeType      `json:"type"`
+	Old          *V2ControlGroup `json:"old"`
+	New          *V2ControlGroup `json:"new"`
+}
+
+// DesiredStateChanged reports whether the instance was started or stopped
+func (change ControlGroupChange) DesiredStateChanged() bool {
+	return change.Old != nil && change.New != nil && change.Old.DesiredState != change.New.DesiredState
+}
+
+// ForceBounced reports whether the instance got a new force bounce token
+func (change ControlGroupChange) ForceBounced() bool {
+	return change.Old != nil && change.New != nil && change.Old.ForceBounce != change.New.ForceBounce
+}
+
+// V1DeploymentChange is a change of a legacy deployment, keyed by
+// `<service>:paasta-<branch>`. `Old` is nil for added deployments and `New`
+// for removed ones
+type V1DeploymentChange struct {
+	Key  string        `json:"key"`
+	Type ChangeType    `json:"type"`
+	Old  *V1Deployment `json:"old"`
+	New  *V1Deployment `json:"new"`
+}
+
+// Changes are differences between two deployments, ordered by deploy and
+// control group or legacy key
+type Changes struct {
+	Deployments []DeploymentChange   `json:"deployments"`
+	Controls    []ControlGroupChange `json:"controls"`
+	V1          []V1DeploymentChange `json:"v1"`
+}
+
+// Diff returns changes from `old` to `new`, including legacy v1 deployments.
+// Nil deployments are treated as empty.
+func Diff(old, new *Deployments) *Changes {
+	if old == nil {
+		old = NewDeployments()
+	}
+	if new == nil {
+		new = NewDeployments()
+	}
+	changes := &Changes{
+		Deployments: []DeploymentChange{},
+		Controls:    []ControlGroupChange{},
+		V1:          []V1DeploymentChange{},
+	}
+	for _, deployGroup := range unionKeys(old.V2.Deployments, new.V2.Deployments) {
+		oldGroup, inOld := old.V2.Deployments[deployGroup]
+		newGroup, inNew := new.V2.Deployments[deployGroup]
+		if inOld && inNew && oldGroup == newGroup {
+			continue
+		}
+		change := DeploymentChange{DeployGroup: deployGroup, Type: changeType(inOld, inNew)}
+		if inOld {
+			change.Old = &oldGroup
+		}
+		if inNew {
+			change.New = &newGroup
+		}
+		changes.Deployments = append(changes.Deployments, change)
+	}
+	for _, controlGroup := range unionKeys(old.V2.Controls, new.V2.Controls) {
+		oldControl, inOld := old.V2.Controls[controlGroup]
+		newControl, inNew := new.V2.Controls[controlGroup]
+		if inOld && inNew && oldControl == newControl {
+			continue
+		}
+		change := ControlGroupChange{ControlGroup: controlGroup, Type: changeType(inOld, inNew)}
+		if inOld {
+			change.Old = &oldControl
+		}
+		if inNew {
+			change.New = &newControl
+		}
+		changes.Controls = append(changes.Controls, change)
+	}
+	for _, key := range unionKeys(old.V1, new.V1) {
+		oldDeployment, inOld := old.V1[key]
+		newDeployment, inNew := new.V1[key]
+		if inOld && inNew && oldDeployment == newDeployment {
+			continue
+		}
+		change := V1DeploymentChange{Key: key, Type: changeType(inOld, inNew)}
+		if inOld {
+			change.Old = &oldDeployment
+		}
+		if inNew {
+			change.New = &newDeployment
+		}
+		changes.V1 = append(changes.V1, change)
+	}
+	return changes
+}
+
+func changeType(inOld, inNew bool) ChangeType {
+	switch {
+	case !inOld:
+		return ChangeAdded
+	case !inNew:
+		return ChangeRemoved
+	default:
+		return ChangeModified
+	}
+}
+
+// unionKeys returns keys of both maps in lexical order
+func unionKeys[V any](old, new map[string]V) []string {
+	keys := make([]string, 0, len(new))
+	for key := range new {
+		keys = append(keys, key)
+	}
+	for key := range old {
+		if _, ok := new[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Empty reports whether there are no changes
+func (changes *Changes) Empty() bool {
+	return len(changes.Deployments) == 0 && len(changes.Controls) == 0 && len(changes.V1) == 0
+}
+
+func describeDeployment(group *V2DeploymentGroup) string {
+	if group.ImageVersion == "" {
+		return group.GitSHA
+	}
+	return fmt.Sprintf("%s (image version %s)", group.GitSHA, group.ImageVersion)
+}
+
+func describeControl(control *V2ControlGroup) string {
+	if control.ForceBounce == "" {
+		return control.DesiredState
+	}
+	return fmt.Sprintf("%s, force_bounce %s", control.DesiredState, control.ForceBounce)
+}
+
+func describeV1Deployment(deployment *V1Deployment) string {
+	if deployment.ForceBounce == "" {
+		return fmt.Sprintf("%s (%s)", deployment.DockerImage, deployment.DesiredState)
+	}
+	return fmt.Sprintf(
+		"%s (%s, force_bounce %s)",
+		deployment.DockerImage, deployment.DesiredState, deployment.ForceBounce,
+	)
+}
+
+// Summary returns one line per change, suitable for deploy notifications
+func (changes *Changes) Summary() []string {
+	lines := []string{}
+	for _, change := range changes.Deployments {
+		switch change.Type {
+		case ChangeAdded:
+			lines = append(lines, fmt.Sprintf(
+				"%s: added at %s", change.DeployGroup, describeDeployment(change.New),
+			))
+		case ChangeRemoved:
+			lines = append(lines, fmt.Sprintf(
+				"%s: removed, was at %s", change.DeployGroup, describeDeployment(change.Old),
+			))
+		default:
+			lines = append(lines, fmt.Sprintf(
+				"%s: %s -> %s", change.DeployGroup,
+				describeDeployment(change.Old), describeDeployment(change.New),
+			))
+		}
+	}
+	for _, change := range changes.Controls {
+		switch change.Type {
+		case ChangeAdded:
+			lines = append(lines, fmt.Sprintf(
+				"%s: added as %s", change.ControlGroup, describeControl(change.New),
+			))
+		case ChangeRemoved:
+			lines = append(lines, fmt.Sprintf(
+				"%s: removed, was %s", change.ControlGroup, describeControl(change.Old),
+			))
+		default:
+			parts := []string{}
+			if change.DesiredStateChanged() {
+				parts = append(parts, fmt.Sprintf(
+					"desired_state %s -> %s", change.Old.DesiredState, change.New.DesiredState,
+				))
+			}
+			if change.ForceBounced() {
+				parts = append(parts, fmt.Sprintf("force_bounce %s", change.New.ForceBounce))
+			}
+			lines = append(lines, fmt.Sprintf("%s: %s", change.ControlGroup, strings.Join(parts, ", ")))
+		}
+	}
+	for _, change := range changes.V1 {
+		switch change.Type {
+		case ChangeAdded:
+			lines = append(lines, fmt.Sprintf(
+				"%s: added at %s", change.Key, describeV1Deployment(change.New),
+			))
+		case ChangeRemoved:
+			lines = append(lines, fmt.Sprintf(
+				"%s: removed, was at %s", change.Key, describeV1Deployment(change.Old),
+			))
+		default:
+			lines = append(lines, fmt.Sprintf(
+				"%s: %s -> %s", change.Key,
+				describeV1Deployment(change.Old), describeV1Deployment(change.New),
+			))
+		}
+	}
+	return lines
+}
+
+// YamlDiff renders changed entries as a unified diff of their YAML, with
+// `context` lines of context
+func (changes *Changes) YamlDiff(context int) (string, error) {
+	old, new := NewDeployments(), NewDeployments()
+	for _, change := range changes.Deployments {
+		if change.Old != nil {
+			old.V2.Deployments[change.DeployGroup] = *change.Old
+		}
+		if change.New != nil {
+			new.V2.Deployments[change.DeployGroup] = *change.New
+		}
+	}
+	for _, change := range changes.Controls {
+		if change.Old != nil {
+			old.V2.Controls[change.ControlGroup] = *change.Old
+		}
+		if change.New != nil {
+			new.V2.Controls[change.ControlGroup] = *change.New
+		}
+	}
+	for _, change := range changes.V1 {
+		if change.Old != nil {
+			old.V1[change.Key] = *change.Old
+		}
+		if change.New != nil {
+			new.V1[change.Key] = *change.New
+		}
+	}
+	return utils.GetYamlDiffForObjects(old, new, context)
+}
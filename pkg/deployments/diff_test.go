@@ -0,0 +1,81 @@
+package deployments
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(test *testing.T) {
+	old := NewDeployments()
+	old.SetDeployment("prod.everything", V2DeploymentGroup{DockerImage: "services-fluffy:paasta-abc123", GitSHA: "abc123"})
+	old.SetDeployment("dev.everything", V2DeploymentGroup{DockerImage: "services-fluffy:paasta-abc123", GitSHA: "abc123"})
+	old.SetDeployment("legacy.main", V2DeploymentGroup{DockerImage: "services-fluffy:paasta-0ld5ha", GitSHA: "0ld5ha"})
+	old.SetControl("fluffy", "norcal-prod", "main", V2ControlGroup{DesiredState: "start"})
+	old.SetControl("fluffy", "norcal-devc", "main", V2ControlGroup{DesiredState: "start"})
+	old.V1["fluffy:paasta-legacy.main"] = V1Deployment{DockerImage: "services-fluffy:paasta-0ld5ha", DesiredState: "start"}
+	old.V1["fluffy:paasta-legacy.batch"] = V1Deployment{DockerImage: "services-fluffy:paasta-0ld5ha", DesiredState: "start"}
+
+	new := old.Copy()
+	new.SetDeployment("prod.everything", V2DeploymentGroup{DockerImage: "services-fluffy:paasta-def456", GitSHA: "def456", ImageVersion: "v2"})
+	new.SetDeployment("prod.canary", V2DeploymentGroup{DockerImage: "services-fluffy:paasta-def456", GitSHA: "def456"})
+	delete(new.V2.Deployments, "legacy.main")
+	new.SetControl("fluffy", "norcal-prod", "main", V2ControlGroup{DesiredState: "stop", ForceBounce: "20210304T050607"})
+	new.SetControl("fluffy", "norcal-prod", "canary", V2ControlGroup{DesiredState: "start"})
+	new.V1["fluffy:paasta-legacy.main"] = V1Deployment{DockerImage: "services-fluffy:paasta-abc123", DesiredState: "stop", ForceBounce: "20210304T050607"}
+	delete(new.V1, "fluffy:paasta-legacy.batch")
+	new.V1["fluffy:paasta-legacy.canary"] = V1Deployment{DockerImage: "services-fluffy:paasta-abc123", DesiredState: "start"}
+
+	changes := Diff(old, new)
+	assert.False(test, changes.Empty())
+	assert.Equal(test, 3, len(changes.Deployments))
+	assert.Equal(test, DeploymentChange{
+		DeployGroup: "legacy.main",
+		Type:        ChangeRemoved,
+		Old:         &V2DeploymentGroup{DockerImage: "services-fluffy:paasta-0ld5ha", GitSHA: "0ld5ha"},
+	}, changes.Deployments[0])
+	assert.Equal(test, ChangeAdded, changes.Deployments[1].Type)
+	assert.True(test, changes.Deployments[2].GitSHAChanged())
+	assert.Equal(test, 2, len(changes.Controls))
+	assert.True(test, changes.Controls[1].DesiredStateChanged())
+	assert.True(test, changes.Controls[1].ForceBounced())
+	assert.Equal(test, 3, len(changes.V1))
+	assert.Equal(test, V1DeploymentChange{
+		Key:  "fluffy:paasta-legacy.batch",
+		Type: ChangeRemoved,
+		Old:  &V1Deployment{DockerImage: "services-fluffy:paasta-0ld5ha", DesiredState: "start"},
+	}, changes.V1[0])
+
+	assert.Equal(test, []string{
+		"legacy.main: removed, was at 0ld5ha",
+		"prod.canary: added at def456",
+		"prod.everything: abc123 -> def456 (image version v2)",
+		"fluffy:norcal-prod.canary: added as start",
+		"fluffy:norcal-prod.main: desired_state start -> stop, force_bounce 20210304T050607",
+		"fluffy:paasta-legacy.batch: removed, was at services-fluffy:paasta-0ld5ha (start)",
+		"fluffy:paasta-legacy.canary: added at services-fluffy:paasta-abc123 (start)",
+		"fluffy:paasta-legacy.main: services-fluffy:paasta-0ld5ha (start) -> " +
+			"services-fluffy:paasta-abc123 (stop, force_bounce 20210304T050607)",
+	}, changes.Summary())
+
+	diff, err := changes.YamlDiff(0)
+	assert.NoError(test, err)
+	for _, line := range []string{
+		"+      desired_state: stop\n",
+		"+      force_bounce: 20210304T050607\n",
+		"-    legacy.main:\n",
+		"+      image_version: v2\n",
+		"-  fluffy:paasta-legacy.batch:\n",
+		"+    docker_image: services-fluffy:paasta-abc123\n",
+	} {
+		assert.True(test, strings.Contains(diff, line), diff)
+	}
+	assert.False(test, strings.Contains(diff, "dev.everything"), diff)
+
+	assert.True(test, Diff(old, old.Copy()).Empty())
+	legacyOnly := old.Copy()
+	legacyOnly.V1["fluffy:paasta-legacy.main"] = new.V1["fluffy:paasta-legacy.main"]
+	assert.False(test, Diff(old, legacyOnly).Empty())
+	assert.Equal(test, 3, len(Diff(nil, old).Deployments))
+}
@@ -0,0 +1,164 @@
+package deployments
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRegistryTimeout limits how long `DigestImageProvider` waits for
+// registries when `Timeout` isn't set
+const DefaultRegistryTimeout = 10 * time.Second
+
+// manifestMediaTypes are manifest formats accepted from registries, digests
+// of manifest lists and indexes are returned for multi-arch images
+var manifestMediaTypes = []string{
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+}
+
+type digestCacheEntry struct {
+	digest  string
+	expires time.Time
+}
+
+// DigestImageProvider is an `ImageProvider` pinning image tags to their
+// immutable `@sha256:` digests, which are resolved through the Docker
+// Registry HTTP API v2. Resolved digests are cached for `CacheTTL`, or
+// forever when it's zero.
+type DigestImageProvider struct {
+	Provider ImageProvider
+	Client   *http.Client
+	Timeout  time.Duration
+	CacheTTL time.Duration
+	// Scheme used to reach registries, https when empty
+	Scheme string
+
+	lock  sync.Mutex
+	cache map[string]digestCacheEntry
+}
+
+// DockerImageURLForDeployGroup returns pullable docker image URL pinned to
+// a digest
+func (provider *DigestImageProvider) DockerImageURLForDeployGroup(deploymentGroup string) (string, error) {
+	image, err := provider.Provider.DockerImageURLForDeployGroup(deploymentGroup)
+	if err != nil {
+		return "", err
+	}
+	return provider.PinDigest(image)
+}
+
+// PinDigest returns `image` with its tag replaced by the digest it points
+// to. Images already pinned to a digest are returned unchanged.
+func (provider *DigestImageProvider) PinDigest(image string) (string, error) {
+	registry, name := splitImageURL(image)
+	if strings.Contains(name, "@") {
+		return image, nil
+	}
+	repository, reference := splitImageReference(name)
+	if registry == "" {
+		return "", fmt.Errorf("Image %s has no registry to resolve its digest", image)
+	}
+
+	digest, ok := provider.cachedDigest(image)
+	if !ok {
+		var err error
+		digest, err = provider.fetchDigest(registry, repository, reference)
+		if err != nil {
+			return "", err
+		}
+		provider.cacheDigest(image, digest)
+	}
+	return fmt.Sprintf("%s/%s@%s", registry, repository, digest), nil
+}
+
+func (provider *DigestImageProvider) cachedDigest(image string) (string, bool) {
+	provider.lock.Lock()
+	defer provider.lock.Unlock()
+	entry, ok := provider.cache[image]
+	if !ok || (!entry.expires.IsZero() && time.Now().After(entry.expires)) {
+		return "", false
+	}
+	return entry.digest, true
+}
+
+func (provider *DigestImageProvider) cacheDigest(image, digest string) {
+	provider.lock.Lock()
+	defer provider.lock.Unlock()
+	if provider.cache == nil {
+		provider.cache = map[string]digestCacheEntry{}
+	}
+	entry := digestCacheEntry{digest: digest}
+	if provider.CacheTTL > 0 {
+		entry.expires = time.Now().Add(provider.CacheTTL)
+	}
+	provider.cache[image] = entry
+}
+
+// fetchDigest asks the registry for the manifest digest of a tag. HEAD
+// requests are tried first, the manifest is downloaded and hashed when the
+// registry doesn't send a `Docker-Content-Digest` header.
+func (provider *DigestImageProvider) fetchDigest(registry, repository, reference string) (string, error) {
+	scheme := provider.Scheme
+	if scheme == "" {
+		scheme = "https"
+	}
+	url := fmt.Sprintf("%s://%s/v2/%s/manifests/%s", scheme, registry, repository, reference)
+
+	digest, err := provider.requestDigest(http.MethodHead, url)
+	if err == nil && digest == "" {
+		digest, err = provider.requestDigest(http.MethodGet, url)
+	}
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(digest, "sha256:") {
+		return "", fmt.Errorf("Unexpected digest %q for %s", digest, url)
+	}
+	return digest, nil
+}
+
+func (provider *DigestImageProvider) requestDigest(method, url string) (string, error) {
+	timeout := provider.Timeout
+	if timeout == 0 {
+		timeout = DefaultRegistryTimeout
+	}
+	client := provider.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("Failed to fetch %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		io.Copy(io.Discard, resp.Body)
+		return "", fmt.Errorf("Failed to fetch %s: %s", url, resp.Status)
+	}
+	if digest := resp.Header.Get("Docker-Content-Digest"); digest != "" || method == http.MethodHead {
+		io.Copy(io.Discard, resp.Body)
+		return digest, nil
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, resp.Body); err != nil {
+		return "", fmt.Errorf("Failed to fetch %s: %v", url, err)
+	}
+	return fmt.Sprintf("sha256:%x", hash.Sum(nil)), nil
+}
@@ -0,0 +1,65 @@
+package deployments
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDigestImageProvider(test *testing.T) {
+	const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	manifest := `{"schemaVersion": 2}`
+	requests := []string{}
+	registry := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		assert.True(test, strings.Contains(r.Header.Get("Accept"), "application/vnd.docker.distribution.manifest.v2+json"))
+		switch r.URL.Path {
+		case "/v2/services-fluffy/manifests/paasta-abc123":
+			w.Header().Set("Docker-Content-Digest", digest)
+		case "/v2/services-fluffy/manifests/paasta-nodigest":
+			if r.Method == http.MethodGet {
+				w.Write([]byte(manifest))
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer registry.Close()
+	host := strings.TrimPrefix(registry.URL, "https://")
+
+	provider := &DigestImageProvider{
+		Provider: staticImageProvider{
+			"prod.everything": host + "/services-fluffy:paasta-abc123",
+			"prod.canary":     host + "/services-fluffy:paasta-nodigest",
+			"pinned":          host + "/services-fluffy@" + digest,
+			"missing":         host + "/services-fluffy:paasta-missing",
+		},
+		Client:   registry.Client(),
+		CacheTTL: time.Hour,
+	}
+
+	for i := 0; i < 2; i++ {
+		image, err := provider.DockerImageURLForDeployGroup("prod.everything")
+		assert.NoError(test, err)
+		assert.Equal(test, host+"/services-fluffy@"+digest, image)
+	}
+	assert.Equal(test, []string{"HEAD /v2/services-fluffy/manifests/paasta-abc123"}, requests)
+
+	image, err := provider.DockerImageURLForDeployGroup("prod.canary")
+	assert.NoError(test, err)
+	assert.Equal(test, host+"/services-fluffy@sha256:c5d902c53b4afcf32ad746fd9d696431650d3fbe8f7b10ca10519543fefd772c", image)
+
+	requests = nil
+	image, err = provider.DockerImageURLForDeployGroup("pinned")
+	assert.NoError(test, err)
+	assert.Equal(test, host+"/services-fluffy@"+digest, image)
+	assert.Empty(test, requests)
+
+	_, err = provider.DockerImageURLForDeployGroup("missing")
+	assert.Error(test, err)
+	assert.True(test, strings.Contains(err.Error(), "404"), err.Error())
+}
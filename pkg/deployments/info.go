@@ -0,0 +1,87 @@
+package deployments
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DeploymentInfo describes what is deployed to a deploy group of a service,
+// regardless of the deployments section it was found in
+type DeploymentInfo struct {
+	Service     string
+	DeployGroup string
+	// DockerImage includes `ImageVersion` when it's set
+	DockerImage  string
+	GitSHA       string
+	ImageVersion string
+	// Legacy is set for deployments found in the v1 section
+	Legacy bool
+}
+
+// VersionedDockerImage returns the docker image with `ImageVersion` appended
+// to its tag, unless it's already there
+func (group V2DeploymentGroup) VersionedDockerImage() string {
+	if group.ImageVersion == "" || strings.HasSuffix(group.DockerImage, "-"+group.ImageVersion) {
+		return group.DockerImage
+	}
+	return fmt.Sprintf("%s-%s", group.DockerImage, group.ImageVersion)
+}
+
+// DockerImageForVersion returns the image name paasta-tools builds for a
+// commit of `service` with an image version, without registry
+func DockerImageForVersion(service, gitSHA, imageVersion string) string {
+	image := DockerImageForGitSHA(service, gitSHA)
+	if imageVersion == "" {
+		return image
+	}
+	return fmt.Sprintf("%s-%s", image, imageVersion)
+}
+
+// v1Keys returns keys a deploy group may have in the v1 section
+func v1Keys(service, deployGroup string) []string {
+	return []string{
+		fmt.Sprintf("%s:paasta-%s", service, deployGroup),
+		fmt.Sprintf("%s:%s", service, deployGroup),
+	}
+}
+
+// gitSHAFromDockerImage returns the commit from a `paasta-<sha>[-<version>]`
+// image tag, or an empty string for other tags
+func gitSHAFromDockerImage(image string) string {
+	idx := strings.LastIndex(image, ":paasta-")
+	if idx < 0 {
+		return ""
+	}
+	tag := image[idx+len(":paasta-"):]
+	if dash := strings.Index(tag, "-"); dash >= 0 {
+		return tag[:dash]
+	}
+	return tag
+}
+
+// DeploymentInfo returns what is deployed to `deployGroup` of `service`. The
+// v2 section is used when it has the deploy group, the legacy v1 section
+// otherwise.
+func (d *Deployments) DeploymentInfo(service, deployGroup string) (DeploymentInfo, bool) {
+	if group, ok := d.V2.Deployments[deployGroup]; ok {
+		return DeploymentInfo{
+			Service:      service,
+			DeployGroup:  deployGroup,
+			DockerImage:  group.VersionedDockerImage(),
+			GitSHA:       group.GitSHA,
+			ImageVersion: group.ImageVersion,
+		}, true
+	}
+	for _, key := range v1Keys(service, deployGroup) {
+		if deployment, ok := d.V1[key]; ok {
+			return DeploymentInfo{
+				Service:     service,
+				DeployGroup: deployGroup,
+				DockerImage: deployment.DockerImage,
+				GitSHA:      gitSHAFromDockerImage(deployment.DockerImage),
+				Legacy:      true,
+			}, true
+		}
+	}
+	return DeploymentInfo{}, false
+}
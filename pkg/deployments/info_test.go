@@ -0,0 +1,116 @@
+package deployments
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/Yelp/paasta-tools-go/pkg/configstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeploymentInfo(test *testing.T) {
+	d := &Deployments{
+		V1: map[string]V1Deployment{
+			"fluffy:paasta-legacy.main": {DockerImage: "services-fluffy:paasta-0ld5ha", DesiredState: "start"},
+			"fluffy:prod.everything":    {DockerImage: "services-fluffy:paasta-shadowed"},
+		},
+		V2: V2DeploymentsConfig{
+			Deployments: map[string]V2DeploymentGroup{
+				"prod.everything": {
+					DockerImage:  "services-fluffy:paasta-abc123",
+					GitSHA:       "abc123",
+					ImageVersion: "20230101T000000",
+				},
+				"prod.canary": {
+					DockerImage:  DockerImageForVersion("fluffy", "def456", "v2"),
+					GitSHA:       "def456",
+					ImageVersion: "v2",
+				},
+			},
+		},
+	}
+
+	info, ok := d.DeploymentInfo("fluffy", "prod.everything")
+	assert.True(test, ok)
+	assert.Equal(test, DeploymentInfo{
+		Service:      "fluffy",
+		DeployGroup:  "prod.everything",
+		DockerImage:  "services-fluffy:paasta-abc123-20230101T000000",
+		GitSHA:       "abc123",
+		ImageVersion: "20230101T000000",
+	}, info)
+
+	info, _ = d.DeploymentInfo("fluffy", "prod.canary")
+	assert.Equal(test, "services-fluffy:paasta-def456-v2", info.DockerImage)
+
+	info, ok = d.DeploymentInfo("fluffy", "legacy.main")
+	assert.True(test, ok)
+	assert.Equal(test, DeploymentInfo{
+		Service:     "fluffy",
+		DeployGroup: "legacy.main",
+		DockerImage: "services-fluffy:paasta-0ld5ha",
+		GitSHA:      "0ld5ha",
+		Legacy:      true,
+	}, info)
+
+	_, ok = d.DeploymentInfo("fluffy", "missing")
+	assert.False(test, ok)
+}
+
+func TestDockerImageURLForDeployGroupVersioned(test *testing.T) {
+	imageProvider := DefaultImageProvider{
+		Service: "fluffy",
+		PaastaConfig: configstore.NewStoreFromMap(map[string]interface{}{
+			"docker_registry": "registry.yelp.com",
+		}),
+		ServiceConfig: configstore.NewStoreFromMap(map[string]interface{}{
+			"v1": map[string]interface{}{
+				"fluffy:paasta-legacy.main": map[string]interface{}{
+					"docker_image":  "services-fluffy:paasta-0ld5ha",
+					"desired_state": "start",
+					"force_bounce":  nil,
+				},
+			},
+			"v2": map[string]interface{}{
+				"deployments": map[string]interface{}{
+					"prod.everything": map[string]interface{}{
+						"docker_image":  "services-fluffy:paasta-abc123",
+						"git_sha":       "abc123",
+						"image_version": "extrastuff",
+					},
+				},
+			},
+		}),
+	}
+
+	url, err := imageProvider.DockerImageURLForDeployGroup("prod.everything")
+	assert.NoError(test, err)
+	assert.Equal(test, "registry.yelp.com/services-fluffy:paasta-abc123-extrastuff", url)
+
+	url, err = imageProvider.DockerImageURLForDeployGroup("legacy.main")
+	assert.NoError(test, err)
+	assert.Equal(test, "registry.yelp.com/services-fluffy:paasta-0ld5ha", url)
+}
+
+func TestDockerImageURLForDeployGroupLegacyOnly(test *testing.T) {
+	root := test.TempDir()
+	if err := os.Mkdir(path.Join(root, "fluffy"), 0755); err != nil {
+		test.Fatal(err)
+	}
+	legacy := `{"v1": {"fluffy:paasta-legacy.main": {"docker_image": "services-fluffy:paasta-0ld5ha", "desired_state": "start", "force_bounce": null}}}`
+	if err := os.WriteFile(path.Join(root, "fluffy", DeploymentsFile), []byte(legacy), 0644); err != nil {
+		test.Fatal(err)
+	}
+	imageProvider := NewImageProviderForService("fluffy", root)
+	imageProvider.PaastaConfig = configstore.NewStoreFromMap(map[string]interface{}{
+		"docker_registry": "registry.yelp.com",
+	})
+
+	url, err := imageProvider.DockerImageURLForDeployGroup("legacy.main")
+	assert.NoError(test, err)
+	assert.Equal(test, "registry.yelp.com/services-fluffy:paasta-0ld5ha", url)
+
+	_, err = imageProvider.DockerImageURLForDeployGroup("prod.everything")
+	assert.Error(test, err)
+}
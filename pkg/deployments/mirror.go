@@ -0,0 +1,61 @@
+package deployments
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RegistryMirrors maps clusters to the mirrors they pull images from,
+// mirrors are keyed by the registry they mirror
+type RegistryMirrors map[string]map[string]string
+
+// MirrorImageProvider is an `ImageProvider` returning images from the
+// registry mirror of `Cluster`. Images of registries without a mirror are
+// returned unchanged.
+type MirrorImageProvider struct {
+	Provider ImageProvider
+	Cluster  string
+	Mirrors  RegistryMirrors
+}
+
+// DockerImageURLForDeployGroup returns pullable docker image URL, with the
+// registry replaced by its mirror
+func (provider *MirrorImageProvider) DockerImageURLForDeployGroup(deploymentGroup string) (string, error) {
+	image, err := provider.Provider.DockerImageURLForDeployGroup(deploymentGroup)
+	if err != nil {
+		return "", err
+	}
+	registry, name := splitImageURL(image)
+	mirror, ok := provider.Mirrors[provider.Cluster][registry]
+	if !ok || registry == "" {
+		return image, nil
+	}
+	return fmt.Sprintf("%s/%s", mirror, name), nil
+}
+
+// splitImageURL splits an image URL into its registry and the image name
+// with tag or digest. The registry is empty when the URL doesn't have one,
+// which is detected the same way docker does.
+func splitImageURL(image string) (string, string) {
+	idx := strings.Index(image, "/")
+	if idx < 0 {
+		return "", image
+	}
+	registry := image[:idx]
+	if !strings.ContainsAny(registry, ".:") && registry != "localhost" {
+		return "", image
+	}
+	return registry, image[idx+1:]
+}
+
+// splitImageReference splits an image name into its repository and its tag
+// or digest reference. Images without a reference use the `latest` tag.
+func splitImageReference(name string) (string, string) {
+	if idx := strings.Index(name, "@"); idx >= 0 {
+		return name[:idx], name[idx+1:]
+	}
+	if idx := strings.LastIndex(name, ":"); idx >= 0 {
+		return name[:idx], name[idx+1:]
+	}
+	return name, "latest"
+}
@@ -0,0 +1,53 @@
+package deployments
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type staticImageProvider map[string]string
+
+func (provider staticImageProvider) DockerImageURLForDeployGroup(deploymentGroup string) (string, error) {
+	image, ok := provider[deploymentGroup]
+	if !ok {
+		return "", fmt.Errorf("Deployment group %s not found", deploymentGroup)
+	}
+	return image, nil
+}
+
+func TestNewImageProviderForService(test *testing.T) {
+	provider := NewImageProviderForService("fluffy", "/srv/soa-configs")
+	assert.Equal(test, "/srv/soa-configs/fluffy", provider.ServiceConfig.Dir)
+	assert.Equal(test, "/nail/etc/services/fluffy", NewDefaultImageProviderForService("fluffy").ServiceConfig.Dir)
+}
+
+func TestMirrorImageProvider(test *testing.T) {
+	provider := &MirrorImageProvider{
+		Provider: staticImageProvider{
+			"prod.everything": "registry.yelp.com/services-fluffy:paasta-abc123",
+			"local":           "services-fluffy:paasta-abc123",
+		},
+		Cluster: "pnw-prod",
+		Mirrors: RegistryMirrors{
+			"pnw-prod": {"registry.yelp.com": "registry-pnw.yelp.com:443"},
+		},
+	}
+
+	image, err := provider.DockerImageURLForDeployGroup("prod.everything")
+	assert.NoError(test, err)
+	assert.Equal(test, "registry-pnw.yelp.com:443/services-fluffy:paasta-abc123", image)
+
+	image, err = provider.DockerImageURLForDeployGroup("local")
+	assert.NoError(test, err)
+	assert.Equal(test, "services-fluffy:paasta-abc123", image)
+
+	provider.Cluster = "norcal-prod"
+	image, err = provider.DockerImageURLForDeployGroup("prod.everything")
+	assert.NoError(test, err)
+	assert.Equal(test, "registry.yelp.com/services-fluffy:paasta-abc123", image)
+
+	_, err = provider.DockerImageURLForDeployGroup("missing")
+	assert.Error(test, err)
+}
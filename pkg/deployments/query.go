@@ -0,0 +1,179 @@
+package deployments
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/Yelp/paasta-tools-go/pkg/soaconfigs"
+)
+
+// v1DeployGroup returns the service and deploy group of a v1 key
+func v1DeployGroup(key string) (string, string) {
+	idx := strings.Index(key, ":")
+	if idx < 0 {
+		return "", key
+	}
+	return key[:idx], strings.TrimPrefix(key[idx+1:], "paasta-")
+}
+
+// deploymentInfos returns deployments of all deploy groups of `service`, v2
+// deploy groups take precedence over v1 ones. v1 deployments of any service
+// are included when `service` is empty.
+func (d *Deployments) deploymentInfos(service string) map[string]DeploymentInfo {
+	infos := map[string]DeploymentInfo{}
+	for key := range d.V1 {
+		v1Service, deployGroup := v1DeployGroup(key)
+		if service != "" && v1Service != service {
+			continue
+		}
+		if info, ok := d.DeploymentInfo(v1Service, deployGroup); ok {
+			infos[deployGroup] = info
+		}
+	}
+	for deployGroup := range d.V2.Deployments {
+		infos[deployGroup], _ = d.DeploymentInfo(service, deployGroup)
+	}
+	return infos
+}
+
+// sortedInfos returns `infos` ordered by deploy group
+func sortedInfos(infos []DeploymentInfo) []DeploymentInfo {
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].DeployGroup < infos[j].DeployGroup
+	})
+	return infos
+}
+
+// DeployGroups returns deploy groups which have a deployment, in lexical
+// order
+func (d *Deployments) DeployGroups() []string {
+	infos := d.deploymentInfos("")
+	groups := make([]string, 0, len(infos))
+	for deployGroup := range infos {
+		groups = append(groups, deployGroup)
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+// MatchDeployGroups returns deploy groups matching `pattern` in lexical
+// order. Patterns use `path.Match` syntax, e.g. `prod.*`.
+func (d *Deployments) MatchDeployGroups(pattern string) ([]string, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	var groups []string
+	for _, deployGroup := range d.DeployGroups() {
+		if ok, _ := path.Match(pattern, deployGroup); ok {
+			groups = append(groups, deployGroup)
+		}
+	}
+	return groups, nil
+}
+
+// FindByGitSHA returns deployments of `service` running commits starting
+// with `prefix`, ordered by deploy group
+func (d *Deployments) FindByGitSHA(service, prefix string) []DeploymentInfo {
+	if prefix == "" {
+		return nil
+	}
+	var infos []DeploymentInfo
+	for _, info := range d.deploymentInfos(service) {
+		if strings.HasPrefix(info.GitSHA, prefix) {
+			infos = append(infos, info)
+		}
+	}
+	return sortedInfos(infos)
+}
+
+// DeployGroupsByGitSHA maps deployed commits to their deploy groups, in
+// lexical order
+func (d *Deployments) DeployGroupsByGitSHA() map[string][]string {
+	res := map[string][]string{}
+	infos := d.deploymentInfos("")
+	for _, deployGroup := range d.DeployGroups() {
+		if info := infos[deployGroup]; info.GitSHA != "" {
+			res[info.GitSHA] = append(res[info.GitSHA], deployGroup)
+		}
+	}
+	return res
+}
+
+// ControlGroupsForDeployGroups maps deploy groups matching `pattern` to the
+// control groups of instances of `service` which run them. Without
+// `instances`, e.g. when soa-configs are not available, the default deploy
+// group `<cluster>.<instance>` of existing control groups is assumed.
+func (d *Deployments) ControlGroupsForDeployGroups(
+	service, pattern string, instances []InstanceDeployGroup,
+) (map[string][]string, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	if instances == nil {
+		instances = d.defaultInstanceDeployGroups(service)
+	}
+	res := map[string][]string{}
+	for _, instance := range instances {
+		if ok, _ := path.Match(pattern, instance.DeployGroup); !ok {
+			continue
+		}
+		res[instance.DeployGroup] = append(
+			res[instance.DeployGroup],
+			ControlGroup(service, instance.Cluster, instance.Instance),
+		)
+	}
+	for _, controlGroups := range res {
+		sort.Strings(controlGroups)
+	}
+	return res, nil
+}
+
+func (d *Deployments) defaultInstanceDeployGroups(service string) []InstanceDeployGroup {
+	var instances []InstanceDeployGroup
+	prefix := service + ":"
+	for controlGroup := range d.V2.Controls {
+		if !strings.HasPrefix(controlGroup, prefix) {
+			continue
+		}
+		branch := strings.TrimPrefix(controlGroup, prefix)
+		idx := strings.Index(branch, ".")
+		if idx < 0 {
+			continue
+		}
+		instances = append(instances, InstanceDeployGroup{
+			Cluster:     branch[:idx],
+			Instance:    branch[idx+1:],
+			DeployGroup: branch,
+		})
+	}
+	return instances
+}
+
+// InstanceDeployGroups returns deploy groups of all kubernetes instances of
+// `service` in soa-configs
+func InstanceDeployGroups(loader *soaconfigs.Loader, service string) ([]InstanceDeployGroup, error) {
+	clusters, err := loader.ListClusters(service, soaconfigs.KubernetesInstanceType)
+	if err != nil {
+		return nil, err
+	}
+	var res []InstanceDeployGroup
+	for _, cluster := range clusters {
+		instances, err := loader.ListInstances(service, soaconfigs.KubernetesInstanceType, cluster)
+		if err != nil {
+			return nil, err
+		}
+		for _, instance := range instances {
+			config, err := loader.LoadKubernetesDeploymentConfig(service, cluster, instance)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, InstanceDeployGroup{
+				Cluster:     cluster,
+				Instance:    instance,
+				DeployGroup: config.GetDeployGroup(),
+			})
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,93 @@
+package deployments
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/Yelp/paasta-tools-go/pkg/soaconfigs"
+	"github.com/stretchr/testify/assert"
+)
+
+func queryTestDeployments() *Deployments {
+	d := NewDeployments()
+	d.V1["fluffy:paasta-legacy.main"] = V1Deployment{DockerImage: "services-fluffy:paasta-0ld5ha"}
+	d.V1["fluffy:paasta-prod.everything"] = V1Deployment{DockerImage: "services-fluffy:paasta-shadowed"}
+	d.SetDeployment("prod.everything", V2DeploymentGroup{DockerImage: "services-fluffy:paasta-abc123", GitSHA: "abc123"})
+	d.SetDeployment("prod.canary", V2DeploymentGroup{DockerImage: "services-fluffy:paasta-abd456", GitSHA: "abd456"})
+	d.SetDeployment("dev.everything", V2DeploymentGroup{DockerImage: "services-fluffy:paasta-abc123", GitSHA: "abc123"})
+	d.SetControl("fluffy", "norcal-prod", "main", V2ControlGroup{DesiredState: "start"})
+	d.SetControl("fluffy", "norcal-devc", "main", V2ControlGroup{DesiredState: "start"})
+	d.SetControl("fluffy", "pnw-prod", "main", V2ControlGroup{DesiredState: "start"})
+	return d
+}
+
+func TestDeployGroups(test *testing.T) {
+	d := queryTestDeployments()
+	assert.Equal(test, []string{"dev.everything", "legacy.main", "prod.canary", "prod.everything"}, d.DeployGroups())
+
+	groups, err := d.MatchDeployGroups("prod.*")
+	assert.NoError(test, err)
+	assert.Equal(test, []string{"prod.canary", "prod.everything"}, groups)
+
+	_, err = d.MatchDeployGroups("prod.[")
+	assert.Error(test, err)
+}
+
+func TestFindByGitSHA(test *testing.T) {
+	d := queryTestDeployments()
+	infos := d.FindByGitSHA("fluffy", "ab")
+	assert.Equal(test, 3, len(infos))
+	assert.Equal(test, "dev.everything", infos[0].DeployGroup)
+	assert.Equal(test, "fluffy", infos[0].Service)
+	assert.Equal(test, "abd456", infos[1].GitSHA)
+
+	infos = d.FindByGitSHA("fluffy", "0ld")
+	assert.Equal(test, []DeploymentInfo{{
+		Service:     "fluffy",
+		DeployGroup: "legacy.main",
+		DockerImage: "services-fluffy:paasta-0ld5ha",
+		GitSHA:      "0ld5ha",
+		Legacy:      true,
+	}}, infos)
+
+	assert.Empty(test, d.FindByGitSHA("fluffy", ""))
+	assert.Empty(test, d.FindByGitSHA("other", "0ld"))
+
+	assert.Equal(test, map[string][]string{
+		"0ld5ha": {"legacy.main"},
+		"abc123": {"dev.everything", "prod.everything"},
+		"abd456": {"prod.canary"},
+	}, d.DeployGroupsByGitSHA())
+}
+
+func TestControlGroupsForDeployGroups(test *testing.T) {
+	d := queryTestDeployments()
+
+	res, err := d.ControlGroupsForDeployGroups("fluffy", "*-prod.*", nil)
+	assert.NoError(test, err)
+	assert.Equal(test, map[string][]string{
+		"norcal-prod.main": {"fluffy:norcal-prod.main"},
+		"pnw-prod.main":    {"fluffy:pnw-prod.main"},
+	}, res)
+
+	loader := &soaconfigs.Loader{FS: fstest.MapFS{
+		"fluffy/kubernetes-norcal-prod.yaml": {Data: []byte("main:\n  deploy_group: prod.everything\ncanary:\n  deploy_group: prod.canary\n")},
+		"fluffy/kubernetes-pnw-prod.yaml":    {Data: []byte("main:\n  deploy_group: prod.everything\n")},
+		"fluffy/kubernetes-norcal-devc.yaml": {Data: []byte("main: {}\n")},
+	}}
+	instances, err := InstanceDeployGroups(loader, "fluffy")
+	assert.NoError(test, err)
+	assert.Equal(test, []InstanceDeployGroup{
+		{Cluster: "norcal-devc", Instance: "main", DeployGroup: "norcal-devc.main"},
+		{Cluster: "norcal-prod", Instance: "canary", DeployGroup: "prod.canary"},
+		{Cluster: "norcal-prod", Instance: "main", DeployGroup: "prod.everything"},
+		{Cluster: "pnw-prod", Instance: "main", DeployGroup: "prod.everything"},
+	}, instances)
+
+	res, err = d.ControlGroupsForDeployGroups("fluffy", "prod.*", instances)
+	assert.NoError(test, err)
+	assert.Equal(test, map[string][]string{
+		"prod.canary":     {"fluffy:norcal-prod.canary"},
+		"prod.everything": {"fluffy:norcal-prod.main", "fluffy:pnw-prod.main"},
+	}, res)
+}
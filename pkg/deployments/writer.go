@@ -0,0 +1,239 @@
+package deployments
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// DeploymentsFile is the name of the file deployments are written to in the
+// service config directory
+const DeploymentsFile = "deployments.json"
+
+// DefaultDesiredState is the desired state of control groups which don't
+// have one yet
+const DefaultDesiredState = DesiredStateStart
+
+// marshalJSON works like json.Marshal without escaping HTML characters, so
+// that custom marshalers don't escape them even when the encoder doesn't
+func marshalJSON(value interface{}) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// MarshalJSON writes an empty image version as null, like paasta-tools does
+func (group V2DeploymentGroup) MarshalJSON() ([]byte, error) {
+	var imageVersion *string
+	if group.ImageVersion != "" {
+		imageVersion = &group.ImageVersion
+	}
+	return marshalJSON(struct {
+		DockerImage  string  `json:"docker_image"`
+		GitSHA       string  `json:"git_sha"`
+		ImageVersion *string `json:"image_version"`
+	}{group.DockerImage, group.GitSHA, imageVersion})
+}
+
+// MarshalJSON writes an empty force bounce as null, like paasta-tools does
+func (control V2ControlGroup) MarshalJSON() ([]byte, error) {
+	var forceBounce *string
+	if control.ForceBounce != "" {
+		forceBounce = &control.ForceBounce
+	}
+	return marshalJSON(struct {
+		DesiredState string  `json:"desired_state"`
+		ForceBounce  *string `json:"force_bounce"`
+	}{control.DesiredState, forceBounce})
+}
+
+// MarshalJSON writes an empty force bounce as null, like paasta-tools does
+func (deployment V1Deployment) MarshalJSON() ([]byte, error) {
+	var forceBounce *string
+	if deployment.ForceBounce != "" {
+		forceBounce = &deployment.ForceBounce
+	}
+	return marshalJSON(struct {
+		DockerImage  string  `json:"docker_image"`
+		DesiredState string  `json:"desired_state"`
+		ForceBounce  *string `json:"force_bounce"`
+	}{deployment.DockerImage, deployment.DesiredState, forceBounce})
+}
+
+// NewDeployments creates empty deployments
+func NewDeployments() *Deployments {
+	return &Deployments{
+		V1: map[string]V1Deployment{},
+		V2: V2DeploymentsConfig{
+			Deployments: map[string]V2DeploymentGroup{},
+			Controls:    map[string]V2ControlGroup{},
+		},
+	}
+}
+
+// DockerImageForGitSHA returns the image name paasta-tools builds for a
+// commit of `service`, without registry
+func DockerImageForGitSHA(service, gitSHA string) string {
+	return fmt.Sprintf("services-%s:paasta-%s", service, gitSHA)
+}
+
+// ControlGroup returns the key of an instance in `V2DeploymentsConfig.Controls`
+func ControlGroup(service, cluster, instance string) string {
+	return makeControlGroup(service, instance, cluster)
+}
+
+// SetDeployment sets the deployment of `deployGroup`
+func (d *Deployments) SetDeployment(deployGroup string, group V2DeploymentGroup) {
+	if d.V2.Deployments == nil {
+		d.V2.Deployments = map[string]V2DeploymentGroup{}
+	}
+	d.V2.Deployments[deployGroup] = group
+}
+
+// SetControl sets the control entry of an instance
+func (d *Deployments) SetControl(service, cluster, instance string, control V2ControlGroup) {
+	if d.V2.Controls == nil {
+		d.V2.Controls = map[string]V2ControlGroup{}
+	}
+	d.V2.Controls[ControlGroup(service, cluster, instance)] = control
+}
+
+// InstanceDeployGroup is an instance of a service and its deploy group
+type InstanceDeployGroup struct {
+	Cluster     string
+	Instance    string
+	DeployGroup string
+}
+
+// GenerateDeployments builds deployments of `service` the same way
+// paasta-tools `generate_deployments_for_service` does. `groups` maps deploy
+// groups to their deployed commits, `controls` are the current control
+// entries keyed by control group. Instances of deploy groups without a
+// deployment are skipped, others get their control entry from `controls` or
+// `DefaultDesiredState` without force bounce. Legacy v1 entries
+// `<service>:paasta-<deploy group>` are filled like paasta-tools does, with
+// the control entry of the last instance of the deploy group.
+func GenerateDeployments(
+	service string,
+	instances []InstanceDeployGroup,
+	groups map[string]V2DeploymentGroup,
+	controls map[string]V2ControlGroup,
+) *Deployments {
+	d := NewDeployments()
+	for _, instance := range instances {
+		group, ok := groups[instance.DeployGroup]
+		if !ok {
+			continue
+		}
+		d.SetDeployment(instance.DeployGroup, group)
+		control, ok := controls[ControlGroup(service, instance.Cluster, instance.Instance)]
+		if !ok {
+			control = V2ControlGroup{DesiredState: DefaultDesiredState}
+		}
+		d.SetControl(service, instance.Cluster, instance.Instance, control)
+		d.V1[v1Keys(service, instance.DeployGroup)[0]] = V1Deployment{
+			DockerImage:  group.DockerImage,
+			DesiredState: control.DesiredState,
+			ForceBounce:  control.ForceBounce,
+		}
+	}
+	return d
+}
+
+// Marshal serializes deployments in the layout of `json.dump` used by
+// paasta-tools: a single line, `", "` and `": "` separators, non-ASCII
+// characters escaped. Top-level and `v2` keys keep the order paasta-tools
+// writes them in, `v1` before `v2` and `deployments` before `controls`, keys
+// of deploy and control groups are sorted so that output is deterministic.
+func (d *Deployments) Marshal() ([]byte, error) {
+	normalized := *d
+	if normalized.V1 == nil {
+		normalized.V1 = map[string]V1Deployment{}
+	}
+	if normalized.V2.Deployments == nil {
+		normalized.V2.Deployments = map[string]V2DeploymentGroup{}
+	}
+	if normalized.V2.Controls == nil {
+		normalized.V2.Controls = map[string]V2ControlGroup{}
+	}
+
+	data, err := marshalJSON(normalized)
+	if err != nil {
+		return nil, err
+	}
+	return pythonJSON(data), nil
+}
+
+// pythonJSON reformats compact JSON to match Python `json.dumps` defaults
+func pythonJSON(compact []byte) []byte {
+	res := make([]byte, 0, len(compact)+len(compact)/4)
+	inString, escaped := false, false
+	for idx := 0; idx < len(compact); {
+		c := compact[idx]
+		if inString && c >= utf8.RuneSelf {
+			r, size := utf8.DecodeRune(compact[idx:])
+			idx += size
+			if r1, r2 := utf16.EncodeRune(r); r1 != utf8.RuneError {
+				res = append(res, fmt.Sprintf(`\u%04x\u%04x`, r1, r2)...)
+			} else {
+				res = append(res, fmt.Sprintf(`\u%04x`, r)...)
+			}
+			continue
+		}
+		idx++
+		res = append(res, c)
+		switch {
+		case escaped:
+			escaped = false
+		case inString && c == '\\':
+			escaped = true
+		case c == '"':
+			inString = !inString
+		case !inString && (c == ':' || c == ','):
+			res = append(res, ' ')
+		}
+	}
+	return res
+}
+
+// Unmarshal parses deployments written by `Marshal` or paasta-tools
+func Unmarshal(data []byte) (*Deployments, error) {
+	d := &Deployments{}
+	if err := json.Unmarshal(data, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// WriteDeployments atomically writes `d` to `deployments.json` in
+// `serviceDir`
+func WriteDeployments(serviceDir string, d *Deployments) error {
+	data, err := d.Marshal()
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(serviceDir, DeploymentsFile+".tmp")
+	if err != nil {
+		return fmt.Errorf("Failed to create temporary file in %s: %v", serviceDir, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("Failed to write %s: %v", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("Failed to write %s: %v", tmp.Name(), err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path.Join(serviceDir, DeploymentsFile))
+}
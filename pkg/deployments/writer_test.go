@@ -0,0 +1,98 @@
+package deployments
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/Yelp/paasta-tools-go/pkg/configstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateDeployments(test *testing.T) {
+	instances := []InstanceDeployGroup{
+		{Cluster: "norcal", Instance: "main", DeployGroup: "prod.everything"},
+		{Cluster: "norcal", Instance: "canary", DeployGroup: "prod.canary"},
+		{Cluster: "pnw", Instance: "main", DeployGroup: "prod.everything"},
+		{Cluster: "pnw", Instance: "batch", DeployGroup: "undeployed"},
+	}
+	groups := map[string]V2DeploymentGroup{
+		"prod.everything": {DockerImage: DockerImageForGitSHA("fluffy", "abc123"), GitSHA: "abc123"},
+		"prod.canary":     {DockerImage: DockerImageForGitSHA("fluffy", "def456"), GitSHA: "def456"},
+	}
+	controls := map[string]V2ControlGroup{
+		"fluffy:norcal.main": {DesiredState: "stop", ForceBounce: "20230101T000000"},
+		"fluffy:pnw.gone":    {DesiredState: "stop"},
+	}
+
+	d := GenerateDeployments("fluffy", instances, groups, controls)
+	data, err := d.Marshal()
+	assert.NoError(test, err)
+	assert.Equal(
+		test,
+		`{"v1": {`+
+			`"fluffy:paasta-prod.canary": {"docker_image": "services-fluffy:paasta-def456", "desired_state": "start", "force_bounce": null}, `+
+			`"fluffy:paasta-prod.everything": {"docker_image": "services-fluffy:paasta-abc123", "desired_state": "start", "force_bounce": null}}, `+
+			`"v2": {"deployments": {`+
+			`"prod.canary": {"docker_image": "services-fluffy:paasta-def456", "git_sha": "def456", "image_version": null}, `+
+			`"prod.everything": {"docker_image": "services-fluffy:paasta-abc123", "git_sha": "abc123", "image_version": null}}, `+
+			`"controls": {`+
+			`"fluffy:norcal.canary": {"desired_state": "start", "force_bounce": null}, `+
+			`"fluffy:norcal.main": {"desired_state": "stop", "force_bounce": "20230101T000000"}, `+
+			`"fluffy:pnw.main": {"desired_state": "start", "force_bounce": null}}}}`,
+		string(data),
+	)
+
+	// round trip
+	parsed, err := Unmarshal(data)
+	assert.NoError(test, err)
+	assert.Equal(test, d, parsed)
+	again, err := parsed.Marshal()
+	assert.NoError(test, err)
+	assert.Equal(test, data, again)
+}
+
+func TestDeploymentsMarshalEscaping(test *testing.T) {
+	d := NewDeployments()
+	d.SetDeployment("dev: <é>, \"😀\"", V2DeploymentGroup{DockerImage: "a&b", GitSHA: "x"})
+	data, err := d.Marshal()
+	assert.NoError(test, err)
+	assert.Equal(
+		test,
+		`{"v1": {}, "v2": {"deployments": {"dev: <\u00e9>, \"\ud83d\ude00\"": {"docker_image": "a&b", "git_sha": "x", "image_version": null}}, "controls": {}}}`,
+		string(data),
+	)
+	parsed, err := Unmarshal(data)
+	assert.NoError(test, err)
+	assert.Equal(test, d, parsed)
+}
+
+func TestDeploymentsMarshalPaastaToolsLayout(test *testing.T) {
+	// deployments.json as written by paasta-tools generate_deployments_for_service
+	data := `{"v1": {"fluffy:paasta-prod.everything": {"docker_image": "services-fluffy:paasta-abc123", "desired_state": "start", "force_bounce": null}}, ` +
+		`"v2": {"deployments": {"prod.everything": {"docker_image": "services-fluffy:paasta-abc123", "git_sha": "abc123", "image_version": null}}, ` +
+		`"controls": {"fluffy:norcal.main": {"desired_state": "start", "force_bounce": "20230101T000000"}}}}`
+	d, err := Unmarshal([]byte(data))
+	assert.NoError(test, err)
+	marshaled, err := d.Marshal()
+	assert.NoError(test, err)
+	assert.Equal(test, data, string(marshaled))
+}
+
+func TestWriteDeployments(test *testing.T) {
+	dir := test.TempDir()
+	d := NewDeployments()
+	d.SetDeployment("prod.everything", V2DeploymentGroup{DockerImage: "services-fluffy:paasta-abc123", GitSHA: "abc123"})
+	d.SetControl("fluffy", "norcal", "main", V2ControlGroup{DesiredState: "start"})
+	assert.NoError(test, WriteDeployments(dir, d))
+
+	data, err := os.ReadFile(path.Join(dir, DeploymentsFile))
+	assert.NoError(test, err)
+	expected, _ := d.Marshal()
+	assert.Equal(test, expected, data)
+
+	store := configstore.NewStore(dir, map[string]string{"v2": "deployments"})
+	loaded, err := deploymentsFromConfig(store)
+	assert.NoError(test, err)
+	assert.Equal(test, d.V2, loaded.V2)
+}
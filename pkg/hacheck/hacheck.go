@@ -0,0 +1,301 @@
+// Package hacheck provides a client for the spool API of hacheck, the
+// healthcheck proxy running on every PaaSTA host. Downing a service spools it
+// on hacheck so that healthchecks for it fail and traffic is drained, upping
+// it removes the spool again.
+package hacheck
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const DEFAULT_HACHECK_PORT = 6666
+const DEFAULT_HACHECK_HOST = "169.254.255.254"
+
+// Options describes a service spool on a hacheck instance, `Reason` and
+// `Expiration` are only used when downing the service. `CheckURI` is the
+// hacheck healthcheck nerve uses for the service, e.g.
+// `/http/<service>/<port>/status`, it's only needed to wait for draining.
+type Options struct {
+	ServiceName string
+	ServicePort int
+	ServiceIP   string
+	Reason      string
+	Expiration  float64
+	HacheckHost string
+	HacheckPort int
+	CheckURI    string
+}
+
+// SpoolStatus is the state of a service spool as reported by hacheck
+type SpoolStatus struct {
+	Status     string  `json:"status"`
+	Reason     string  `json:"reason,omitempty"`
+	Expiration float64 `json:"expiration,omitempty"`
+	Creation   float64 `json:"creation,omitempty"`
+}
+
+// AddFlags registers flags selecting the service and the hacheck instance
+func (opts *Options) AddFlags(flags *flag.FlagSet) {
+	flags.StringVar(&opts.ServiceName, "service", "", "Service to set status for")
+	flags.IntVar(&opts.ServicePort, "servicePort", 0, "Port to set status for")
+	flags.StringVar(&opts.ServiceIP, "serviceIP", "", "IP to set status for")
+	flags.StringVar(&opts.HacheckHost, "host", DEFAULT_HACHECK_HOST, "Host that hacheck is running on")
+	flags.IntVar(&opts.HacheckPort, "port", DEFAULT_HACHECK_PORT, "Port that hacheck is running on")
+}
+
+// Validate checks that options registered by `AddFlags` are usable
+func (opts *Options) Validate() error {
+	if opts.ServiceName == "" {
+		return fmt.Errorf("Service name required")
+	}
+	return nil
+}
+
+func (opts *Options) spoolURL() string {
+	return fmt.Sprintf("http://%s:%d/spool/%s/%d/", opts.HacheckHost, opts.HacheckPort, opts.ServiceName, opts.ServicePort)
+}
+
+func (opts *Options) checkURL() string {
+	return fmt.Sprintf("http://%s:%d/%s", opts.HacheckHost, opts.HacheckPort, strings.TrimPrefix(opts.CheckURI, "/"))
+}
+
+func (opts *Options) newRequest(method string, data url.Values) (*http.Request, error) {
+	return opts.newRequestForURL(method, opts.spoolURL(), data)
+}
+
+func (opts *Options) newRequestForURL(method, url string, data url.Values) (*http.Request, error) {
+	var req *http.Request
+	var err error
+	if data != nil {
+		req, err = http.NewRequest(method, url, strings.NewReader(data.Encode()))
+	} else {
+		req, err = http.NewRequest(method, url, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if data != nil {
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if opts.ServiceIP != "" {
+		req.Header.Set("X-Nerve-Check-IP", opts.ServiceIP)
+	}
+	return req, nil
+}
+
+// DownRequest builds a request spooling the service down
+func DownRequest(opts *Options) (*http.Request, error) {
+	data := url.Values{}
+	data.Set("status", "down")
+	data.Set("reason", opts.Reason)
+	if opts.Expiration > 0 {
+		data.Set("expiration", fmt.Sprintf("%.f", opts.Expiration))
+	}
+	return opts.newRequest("POST", data)
+}
+
+// UpRequest builds a request removing the service spool
+func UpRequest(opts *Options) (*http.Request, error) {
+	data := url.Values{}
+	data.Set("status", "up")
+	return opts.newRequest("POST", data)
+}
+
+// QueryRequest builds a request fetching the current service spool
+func QueryRequest(opts *Options) (*http.Request, error) {
+	return opts.newRequest("GET", nil)
+}
+
+// HealthcheckRequest builds a request running the hacheck healthcheck of
+// the service the way nerve does
+func HealthcheckRequest(opts *Options) (*http.Request, error) {
+	if opts.CheckURI == "" {
+		return nil, fmt.Errorf("no healthcheck URI for %s:%d", opts.ServiceName, opts.ServicePort)
+	}
+	return opts.newRequestForURL("GET", opts.checkURL(), nil)
+}
+
+// ResponseError is returned when hacheck rejects a request
+type ResponseError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *ResponseError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("hacheck returned %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("hacheck returned %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Body)
+}
+
+// maxErrorBodySize limits how much of a rejected response is kept in errors
+const maxErrorBodySize = 4096
+
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	if err != nil {
+		return fmt.Errorf("reading hacheck response: %v", err)
+	}
+	return &ResponseError{
+		StatusCode: resp.StatusCode,
+		Body:       strings.TrimSpace(string(body)),
+	}
+}
+
+// Client sends spool requests to hacheck
+type Client struct {
+	HTTPClient *http.Client
+}
+
+// NewClient creates a new hacheck client using `http.DefaultClient`
+func NewClient() *Client {
+	return &Client{HTTPClient: http.DefaultClient}
+}
+
+// do sends req and returns the response if hacheck accepted it, errors other
+// than `*ResponseError` are transport errors
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkResponse(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Down marks the service as down
+func (c *Client) Down(opts *Options) error {
+	req, err := DownRequest(opts)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// Up marks the service as up by removing its spool
+func (c *Client) Up(opts *Options) error {
+	req, err := UpRequest(opts)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// Query returns the current spool state of the service
+func (c *Client) Query(opts *Options) (*SpoolStatus, error) {
+	req, err := QueryRequest(opts)
+	if err != nil {
+		return nil, err
+	}
+	return c.query(req)
+}
+
+func (c *Client) query(req *http.Request) (*SpoolStatus, error) {
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	status := &SpoolStatus{}
+	if err := json.NewDecoder(resp.Body).Decode(status); err != nil {
+		return nil, fmt.Errorf("decoding spool status: %v", err)
+	}
+	return status, nil
+}
+
+// Healthcheck runs the hacheck healthcheck of the service and reports
+// whether it passed, i.e. whether nerve keeps the service registered
+func (c *Client) Healthcheck(opts *Options) (bool, error) {
+	req, err := HealthcheckRequest(opts)
+	if err != nil {
+		return false, err
+	}
+	return c.healthcheck(req)
+}
+
+func (c *Client) healthcheck(req *http.Request) (bool, error) {
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return resp.StatusCode >= 200 && resp.StatusCode < 300, nil
+}
+
+// WaitForDown polls the hacheck healthcheck of the service every `interval`
+// until it fails, so that nerve deregisters the service and traffic drains,
+// or ctx is done. Without `CheckURI` the spool state is polled until the
+// service is reported down instead. Errors other than a cancelled ctx don't
+// stop polling, the last one is returned together with the ctx error.
+func (c *Client) WaitForDown(ctx context.Context, opts *Options, interval time.Duration) error {
+	isDown := func(req *http.Request) (bool, error) {
+		healthy, err := c.healthcheck(req)
+		return !healthy, err
+	}
+	req, err := HealthcheckRequest(opts)
+	if opts.CheckURI == "" {
+		isDown = func(req *http.Request) (bool, error) {
+			status, err := c.query(req)
+			if err != nil {
+				return false, err
+			}
+			return status.Status == "down", nil
+		}
+		req, err = QueryRequest(opts)
+	}
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var lastErr error
+	for {
+		down, err := isDown(req)
+		if err == nil && down {
+			return nil
+		}
+		lastErr = err
+		select {
+		case <-ctx.Done():
+			if lastErr != nil {
+				return fmt.Errorf("waiting for %s:%d to be down: %w (last error: %v)", opts.ServiceName, opts.ServicePort, ctx.Err(), lastErr)
+			}
+			return fmt.Errorf("waiting for %s:%d to be down: %w", opts.ServiceName, opts.ServicePort, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package hacheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDownRequest(t *testing.T) {
+	opts := &Options{
+		ServiceName: "foo",
+		ServicePort: 12345,
+		ServiceIP:   "10.10.1.2",
+		Reason:      "testing",
+		HacheckHost: "localhost",
+		HacheckPort: 3333,
+		Expiration:  1565214667,
+	}
+	request, err := DownRequest(opts)
+	if err != nil {
+		t.Errorf("Error %s", err)
+	}
+
+	if request.Method != "POST" {
+		t.Errorf("Incorrect method, got: %s, want: POST", request.Method)
+	}
+
+	expectedURL := &url.URL{
+		Scheme: "http",
+		Host:   "localhost:3333",
+		Path:   "/spool/foo/12345/",
+	}
+	if !reflect.DeepEqual(request.URL, expectedURL) {
+		t.Errorf("Incorrect URL, got: %v, want: %v", request.URL, expectedURL)
+	}
+
+	request.ParseForm()
+	expectedForm := url.Values{
+		"status":     {"down"},
+		"reason":     {"testing"},
+		"expiration": {"1565214667"},
+	}
+	if !reflect.DeepEqual(request.PostForm, expectedForm) {
+		t.Errorf("Incorrect request body, got: %v, want: %v", request.PostForm, expectedForm)
+	}
+
+	ip_header := request.Header.Get("X-Nerve-Check-IP")
+	if ip_header != opts.ServiceIP {
+		t.Errorf("Incorrect IP header value, got: %v, want: %v", ip_header, opts.ServiceIP)
+	}
+}
+
+func TestUpRequest(t *testing.T) {
+	opts := &Options{
+		ServiceName: "foo",
+		ServicePort: 12345,
+		HacheckHost: "localhost",
+		HacheckPort: 3333,
+	}
+	request, err := UpRequest(opts)
+	if err != nil {
+		t.Errorf("Error %s", err)
+	}
+
+	if request.Method != "POST" {
+		t.Errorf("Incorrect method, got: %s, want: POST", request.Method)
+	}
+
+	request.ParseForm()
+	expectedForm := url.Values{"status": {"up"}}
+	if !reflect.DeepEqual(request.PostForm, expectedForm) {
+		t.Errorf("Incorrect request body, got: %v, want: %v", request.PostForm, expectedForm)
+	}
+
+	ip_header := request.Header.Get("X-Nerve-Check-IP")
+	if ip_header != "" {
+		t.Errorf("Unexpected IP header value: %v", ip_header)
+	}
+}
+
+// fakeHacheck keeps spool state in memory and serves it like hacheck does
+func fakeHacheck(t *testing.T, spools map[string]*SpoolStatus) *Options {
+	lock := sync.Mutex{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		key := r.URL.Path + r.Header.Get("X-Nerve-Check-IP")
+		if !strings.HasPrefix(r.URL.Path, "/spool/") {
+			// healthchecks fail while the service is spooled down
+			parts := strings.Split(r.URL.Path, "/")
+			key = fmt.Sprintf("/spool/%s/%s/%s", parts[2], parts[3], r.Header.Get("X-Nerve-Check-IP"))
+			if _, ok := spools[key]; ok {
+				http.Error(w, "Service in down state", http.StatusServiceUnavailable)
+			}
+			return
+		}
+		switch r.Method {
+		case "GET":
+			status, ok := spools[key]
+			if !ok {
+				status = &SpoolStatus{Status: "up"}
+			}
+			fmt.Fprintf(w, `{"status": %q, "reason": %q, "expiration": %f}`, status.Status, status.Reason, status.Expiration)
+		case "POST":
+			r.ParseForm()
+			if r.PostForm.Get("status") == "up" {
+				delete(spools, key)
+				return
+			}
+			expiration, _ := strconv.ParseFloat(r.PostForm.Get("expiration"), 64)
+			spools[key] = &SpoolStatus{
+				Status:     "down",
+				Reason:     r.PostForm.Get("reason"),
+				Expiration: expiration,
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	serverURL, _ := url.Parse(server.URL)
+	port, _ := strconv.Atoi(serverURL.Port())
+	return &Options{
+		ServiceName: "foo",
+		ServicePort: 12345,
+		ServiceIP:   "10.10.1.2",
+		HacheckHost: serverURL.Hostname(),
+		HacheckPort: port,
+	}
+}
+
+func TestClient(t *testing.T) {
+	spools := map[string]*SpoolStatus{}
+	opts := fakeHacheck(t, spools)
+	client := NewClient()
+
+	status, err := client.Query(opts)
+	if err != nil {
+		t.Fatalf("Error %s", err)
+	}
+	if status.Status != "up" {
+		t.Errorf("Incorrect status, got: %v, want: up", status.Status)
+	}
+
+	opts.Reason = "testing"
+	opts.Expiration = 1565214667
+	if err := client.Down(opts); err != nil {
+		t.Fatalf("Error %s", err)
+	}
+	status, err = client.Query(opts)
+	if err != nil {
+		t.Fatalf("Error %s", err)
+	}
+	expected := &SpoolStatus{Status: "down", Reason: "testing", Expiration: 1565214667}
+	if !reflect.DeepEqual(status, expected) {
+		t.Errorf("Incorrect status, got: %+v, want: %+v", status, expected)
+	}
+
+	if err := client.Up(opts); err != nil {
+		t.Fatalf("Error %s", err)
+	}
+	if len(spools) != 0 {
+		t.Errorf("Spool was not removed: %+v", spools)
+	}
+}
+
+func TestClientRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unknown service", http.StatusNotFound)
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+	port, _ := strconv.Atoi(serverURL.Port())
+	opts := &Options{ServiceName: "foo", HacheckHost: serverURL.Hostname(), HacheckPort: port}
+
+	err := NewClient().Down(opts)
+	respErr, ok := err.(*ResponseError)
+	if !ok {
+		t.Fatalf("Expected *ResponseError, got: %#v", err)
+	}
+	expected := &ResponseError{StatusCode: 404, Body: "unknown service"}
+	if !reflect.DeepEqual(respErr, expected) {
+		t.Errorf("Incorrect error, got: %+v, want: %+v", respErr, expected)
+	}
+}
+
+func TestWaitForDown(t *testing.T) {
+	spools := map[string]*SpoolStatus{}
+	opts := fakeHacheck(t, spools)
+	client := NewClient()
+
+	for _, checkURI := range []string{"", "/http/foo/12345/status"} {
+		opts.CheckURI = checkURI
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		err := client.WaitForDown(ctx, opts, time.Millisecond)
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected deadline to be exceeded with check URI %q, got: %v", checkURI, err)
+		}
+	}
+
+	opts.Reason = "testing"
+	if err := client.Down(opts); err != nil {
+		t.Fatalf("Error %s", err)
+	}
+	// with a check URI the healthcheck is polled, otherwise the spool state
+	for _, checkURI := range []string{"", "/http/foo/12345/status"} {
+		opts.CheckURI = checkURI
+		if err := client.WaitForDown(context.Background(), opts, time.Millisecond); err != nil {
+			t.Errorf("Error with check URI %q: %s", checkURI, err)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package hacheck
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DEFAULT_NERVE_CONFIG = "/etc/nerve/nerve.conf.json"
+
+// Target identifies a single service spool on a host, `CheckURI` is the
+// hacheck healthcheck nerve uses for it when known
+type Target struct {
+	ServiceName string `json:"service"`
+	ServicePort int    `json:"service_port"`
+	ServiceIP   string `json:"service_ip,omitempty"`
+	CheckURI    string `json:"check_uri,omitempty"`
+}
+
+func (t Target) String() string {
+	return fmt.Sprintf("%s:%d", t.ServiceName, t.ServicePort)
+}
+
+// WithTarget returns a copy of opts pointing at target, `ServiceIP` and
+// `CheckURI` from opts are kept if target doesn't have them
+func (opts *Options) WithTarget(target Target) *Options {
+	res := *opts
+	res.ServiceName = target.ServiceName
+	res.ServicePort = target.ServicePort
+	if target.ServiceIP != "" {
+		res.ServiceIP = target.ServiceIP
+	}
+	if target.CheckURI != "" {
+		res.CheckURI = target.CheckURI
+	}
+	return &res
+}
+
+// ParseTargets reads `service:port` pairs, one per line. Empty lines and
+// lines starting with `#` are ignored.
+func ParseTargets(reader io.Reader) ([]Target, error) {
+	targets := []Target{}
+	scanner := bufio.NewScanner(reader)
+	lineno := 0
+	for scanner.Scan() {
+		lineno++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		idx := strings.LastIndex(line, ":")
+		if idx <= 0 {
+			return nil, fmt.Errorf("line %d: expected service:port, got %q", lineno, line)
+		}
+		port, err := strconv.Atoi(line[idx+1:])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid port in %q: %v", lineno, line, err)
+		}
+		targets = append(targets, Target{ServiceName: line[:idx], ServicePort: port})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return targets, nil
+}
+
+type nerveCheck struct {
+	URI string `json:"uri"`
+}
+
+type nerveService struct {
+	Host   string       `json:"host"`
+	Port   int          `json:"port"`
+	Checks []nerveCheck `json:"checks"`
+}
+
+type nerveConfig struct {
+	Services map[string]nerveService `json:"services"`
+}
+
+// targetFromCheckURI parses hacheck check URIs generated for nerve, which
+// look like `/<mode>/<service>/<port>/<healthcheck uri>`
+func targetFromCheckURI(uri string) (Target, bool) {
+	parts := strings.SplitN(strings.TrimPrefix(uri, "/"), "/", 4)
+	if len(parts) < 3 || parts[1] == "" {
+		return Target{}, false
+	}
+	port, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return Target{}, false
+	}
+	return Target{ServiceName: parts[1], ServicePort: port, CheckURI: uri}, true
+}
+
+// DiscoverNerveTargets lists services registered on this host according to
+// the nerve configuration at `path`
+func DiscoverNerveTargets(path string) ([]Target, error) {
+	reader, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open %s: %v", path, err)
+	}
+	defer reader.Close()
+
+	config := &nerveConfig{}
+	if err := json.NewDecoder(reader).Decode(config); err != nil {
+		return nil, fmt.Errorf("Failed to parse %s: %v", path, err)
+	}
+
+	// the same service is registered once per discovery location, dedupe
+	// ignoring check URIs, the first check of a target is kept
+	seen := map[Target]bool{}
+	targets := []Target{}
+	for _, service := range config.Services {
+		for _, check := range service.Checks {
+			target, ok := targetFromCheckURI(check.URI)
+			if !ok {
+				continue
+			}
+			target.ServiceIP = service.Host
+			key := target
+			key.CheckURI = ""
+			if !seen[key] {
+				seen[key] = true
+				targets = append(targets, target)
+			}
+		}
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		if targets[i].ServiceName != targets[j].ServiceName {
+			return targets[i].ServiceName < targets[j].ServiceName
+		}
+		if targets[i].ServicePort != targets[j].ServicePort {
+			return targets[i].ServicePort < targets[j].ServicePort
+		}
+		return targets[i].ServiceIP < targets[j].ServiceIP
+	})
+	return targets, nil
+}
+
+// TargetResult is the outcome of a bulk operation for a single target
+type TargetResult struct {
+	Target Target
+	Err    error
+}
+
+// forEachTarget calls fn for every target with at most `parallelism` calls in
+// flight, results are returned in the order of targets
+func forEachTarget(opts *Options, targets []Target, parallelism int, fn func(*Options) error) []TargetResult {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	results := make([]TargetResult, len(targets))
+	sem := make(chan struct{}, parallelism)
+	wg := sync.WaitGroup{}
+	for idx, target := range targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(idx int, target Target) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			targetOpts := opts.WithTarget(target)
+			target.ServiceIP = targetOpts.ServiceIP
+			target.CheckURI = targetOpts.CheckURI
+			results[idx] = TargetResult{
+				Target: target,
+				Err:    fn(targetOpts),
+			}
+		}(idx, target)
+	}
+	wg.Wait()
+	return results
+}
+
+// DownAll downs every target using `Reason` and `Expiration` from opts, with
+// at most `parallelism` requests in flight. Results are returned in the order
+// of targets and failures don't stop other targets from being downed.
+func (c *Client) DownAll(opts *Options, targets []Target, parallelism int) []TargetResult {
+	return forEachTarget(opts, targets, parallelism, c.Down)
+}
+
+// WaitForDownAll waits for healthchecks of every target to fail, see
+// `WaitForDown`
+func (c *Client) WaitForDownAll(ctx context.Context, opts *Options, targets []Target, parallelism int, interval time.Duration) []TargetResult {
+	return forEachTarget(opts, targets, parallelism, func(targetOpts *Options) error {
+		return c.WaitForDown(ctx, targetOpts, interval)
+	})
+}
@@ -0,0 +1,112 @@
+package hacheck
+
+import (
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTargets(t *testing.T) {
+	input := `
+# maintenance list
+foo.main:12345
+  bar.canary:23456
+
+`
+	targets, err := ParseTargets(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Error %s", err)
+	}
+	expected := []Target{
+		{ServiceName: "foo.main", ServicePort: 12345},
+		{ServiceName: "bar.canary", ServicePort: 23456},
+	}
+	if !reflect.DeepEqual(targets, expected) {
+		t.Errorf("Incorrect targets, got: %+v, want: %+v", targets, expected)
+	}
+
+	for _, input := range []string{"foo", ":123", "foo:bar"} {
+		if _, err := ParseTargets(strings.NewReader(input)); err == nil {
+			t.Errorf("Expected %q to fail", input)
+		}
+	}
+}
+
+func TestDiscoverNerveTargets(t *testing.T) {
+	config := `{
+  "services": {
+    "foo.main.norcal:10.1.2.3.31000.new": {
+      "host": "10.1.2.3",
+      "port": 31000,
+      "checks": [{"uri": "/http/foo.main/31000/status", "host": "127.0.0.1", "port": 6666}]
+    },
+    "foo.main.superregion:10.1.2.3.31000.new": {
+      "host": "10.1.2.3",
+      "port": 31000,
+      "checks": [{"uri": "/http/foo.main/31000/status", "host": "127.0.0.1", "port": 6666}]
+    },
+    "bar.main.norcal:10.1.2.4.31001.new": {
+      "host": "10.1.2.4",
+      "port": 31001,
+      "checks": [{"uri": "/tcp/bar.main/31001/", "host": "127.0.0.1", "port": 6666}]
+    },
+    "broken": {
+      "checks": [{"uri": "/nonsense"}]
+    }
+  }
+}`
+	configPath := path.Join(t.TempDir(), "nerve.conf.json")
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	targets, err := DiscoverNerveTargets(configPath)
+	if err != nil {
+		t.Fatalf("Error %s", err)
+	}
+	expected := []Target{
+		{ServiceName: "bar.main", ServicePort: 31001, ServiceIP: "10.1.2.4", CheckURI: "/tcp/bar.main/31001/"},
+		{ServiceName: "foo.main", ServicePort: 31000, ServiceIP: "10.1.2.3", CheckURI: "/http/foo.main/31000/status"},
+	}
+	if !reflect.DeepEqual(targets, expected) {
+		t.Errorf("Incorrect targets, got: %+v, want: %+v", targets, expected)
+	}
+}
+
+func TestDownAll(t *testing.T) {
+	spools := map[string]*SpoolStatus{}
+	opts := fakeHacheck(t, spools)
+	opts.Reason = "maintenance"
+
+	targets := []Target{
+		{ServiceName: "foo", ServicePort: 1},
+		{ServiceName: "bar", ServicePort: 2, ServiceIP: "10.0.0.2"},
+		{ServiceName: "baz", ServicePort: 3},
+	}
+	results := NewClient().DownAll(opts, targets, 2)
+	if len(results) != len(targets) {
+		t.Fatalf("Incorrect number of results, got: %d, want: %d", len(results), len(targets))
+	}
+	for idx, res := range results {
+		if res.Err != nil {
+			t.Errorf("Error downing %s: %s", res.Target, res.Err)
+		}
+		if res.Target.ServiceName != targets[idx].ServiceName {
+			t.Errorf("Results out of order, got: %s, want: %s", res.Target, targets[idx])
+		}
+	}
+	if results[0].Target.ServiceIP != opts.ServiceIP {
+		t.Errorf("Incorrect default IP, got: %s, want: %s", results[0].Target.ServiceIP, opts.ServiceIP)
+	}
+
+	expectedSpools := map[string]*SpoolStatus{
+		"/spool/foo/1/10.10.1.2": {Status: "down", Reason: "maintenance"},
+		"/spool/bar/2/10.0.0.2":  {Status: "down", Reason: "maintenance"},
+		"/spool/baz/3/10.10.1.2": {Status: "down", Reason: "maintenance"},
+	}
+	if !reflect.DeepEqual(spools, expectedSpools) {
+		t.Errorf("Incorrect spools, got: %+v, want: %+v", spools, expectedSpools)
+	}
+}
@@ -0,0 +1,71 @@
+package soaconfigs
+
+import (
+	"fmt"
+
+	"github.com/Yelp/paasta-tools-go/pkg/containerspec"
+	iam_role "github.com/Yelp/paasta-tools-go/pkg/iam_roles"
+	"github.com/Yelp/paasta-tools-go/pkg/volumes"
+)
+
+// KubernetesInstanceType is the instance type of `kubernetes-<cluster>.yaml`
+const KubernetesInstanceType = "kubernetes"
+
+// KubernetesDeploymentConfig is the config of a kubernetes instance
+type KubernetesDeploymentConfig struct {
+	containerspec.PaastaContainerSpec
+	iam_role.IamRoleConfig
+
+	Service  string `json:"-"`
+	Cluster  string `json:"-"`
+	Instance string `json:"-"`
+
+	DeployGroup     string            `json:"deploy_group,omitempty"`
+	Instances       *int              `json:"instances,omitempty"`
+	MinInstances    *int              `json:"min_instances,omitempty"`
+	MaxInstances    *int              `json:"max_instances,omitempty"`
+	Cmd             string            `json:"cmd,omitempty"`
+	Args            []string          `json:"args,omitempty"`
+	Env             map[string]string `json:"env,omitempty"`
+	ExtraVolumes    []volumes.Volume  `json:"extra_volumes,omitempty"`
+	Registrations   []string          `json:"registrations,omitempty"`
+	HealthcheckMode string            `json:"healthcheck_mode,omitempty"`
+}
+
+// BranchForInstance returns the default deploy group of an instance
+func BranchForInstance(cluster, instance string) string {
+	return fmt.Sprintf("%s.%s", cluster, instance)
+}
+
+// GetDeployGroup returns the configured deploy group, or `<cluster>.<instance>`
+// like paasta-tools when it isn't set
+func (c *KubernetesDeploymentConfig) GetDeployGroup() string {
+	if c.DeployGroup != "" {
+		return c.DeployGroup
+	}
+	return BranchForInstance(c.Cluster, c.Instance)
+}
+
+// GetRegistrations returns smartstack namespaces the instance registers in,
+// `<service>.<instance>` by default
+func (c *KubernetesDeploymentConfig) GetRegistrations() []string {
+	if len(c.Registrations) > 0 {
+		return c.Registrations
+	}
+	return []string{fmt.Sprintf("%s.%s", c.Service, c.Instance)}
+}
+
+// LoadKubernetesDeploymentConfig loads config of a kubernetes instance with
+// the deploy group resolved
+func (l *Loader) LoadKubernetesDeploymentConfig(service, cluster, instance string) (*KubernetesDeploymentConfig, error) {
+	config := &KubernetesDeploymentConfig{}
+	err := l.LoadInstanceConfig(service, KubernetesInstanceType, cluster, instance, config)
+	if err != nil {
+		return nil, err
+	}
+	config.Service = service
+	config.Cluster = cluster
+	config.Instance = instance
+	config.DeployGroup = config.GetDeployGroup()
+	return config, nil
+}
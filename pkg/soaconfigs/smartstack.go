@@ -0,0 +1,43 @@
+package soaconfigs
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// SmartstackNamespace is the config of a namespace in `smartstack.yaml`
+type SmartstackNamespace struct {
+	ProxyPort        *int     `json:"proxy_port,omitempty"`
+	Mode             string   `json:"mode,omitempty"`
+	Healthcheck      string   `json:"healthcheck_uri,omitempty"`
+	TimeoutServerMs  *int     `json:"timeout_server_ms,omitempty"`
+	TimeoutConnectMs *int     `json:"timeout_connect_ms,omitempty"`
+	Discover         string   `json:"discover,omitempty"`
+	Advertise        []string `json:"advertise,omitempty"`
+}
+
+// ReadSmartstack returns namespaces defined in `smartstack.yaml` of
+// `service`, templates are skipped
+func (l *Loader) ReadSmartstack(service string) (map[string]SmartstackNamespace, error) {
+	filename := path.Join(service, "smartstack.yaml")
+	config, err := l.readYaml(filename)
+	if err != nil {
+		return nil, err
+	}
+	for key := range config {
+		if strings.HasPrefix(key, "_") {
+			delete(config, key)
+		}
+	}
+	data, err := json.Marshal(normalize(config))
+	if err != nil {
+		return nil, err
+	}
+	namespaces := map[string]SmartstackNamespace{}
+	if err := json.Unmarshal(data, &namespaces); err != nil {
+		return nil, fmt.Errorf("Failed to decode %s: %v", filename, err)
+	}
+	return namespaces, nil
+}
@@ -0,0 +1,182 @@
+// Package soaconfigs loads service instance configs from soa-configs, e.g.
+// `/nail/etc/services/<service>/kubernetes-<cluster>.yaml`, the same way
+// paasta-tools does:
+//
+//  1. `service.yaml` of the service provides defaults for all instances
+//  2. instance config is read from `<instance type>-<cluster>.yaml` and deep
+//     merged on top of the defaults
+//  3. top-level keys starting with `_` are templates, which can be reused
+//     with YAML anchors and merge keys, and are never treated as instances
+package soaconfigs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/Yelp/paasta-tools-go/pkg/configstore"
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultRoot is where soa-configs are checked out
+const DefaultRoot = "/nail/etc/services"
+
+// ErrInstanceNotFound is returned when an instance isn't defined for the
+// requested cluster, use `errors.Is` to check for it
+var ErrInstanceNotFound = errors.New("instance not found")
+
+// Loader reads soa-configs from `FS`, where each service has a directory
+type Loader struct {
+	FS fs.FS
+}
+
+// NewLoader creates a loader reading soa-configs from `root` on disk
+func NewLoader(root string) *Loader {
+	return &Loader{FS: os.DirFS(root)}
+}
+
+// NewDefaultLoader creates a loader reading soa-configs from `DefaultRoot`
+func NewDefaultLoader() *Loader {
+	return NewLoader(DefaultRoot)
+}
+
+// readYaml decodes `filename` into a dictionary, missing files are empty
+func (l *Loader) readYaml(filename string) (map[string]interface{}, error) {
+	data, err := fs.ReadFile(l.FS, filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]interface{}{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read %s: %v", filename, err)
+	}
+	value := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("Failed to parse %s: %v", filename, err)
+	}
+	return value, nil
+}
+
+// ReadServiceConfig returns `service.yaml` of `service`, it's empty when
+// the service has no such file
+func (l *Loader) ReadServiceConfig(service string) (map[string]interface{}, error) {
+	config, err := l.readYaml(path.Join(service, "service.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	return normalize(config).(map[string]interface{}), nil
+}
+
+func instanceConfigFile(service, instanceType, cluster string) string {
+	return path.Join(service, fmt.Sprintf("%s-%s.yaml", instanceType, cluster))
+}
+
+// ListClusters returns clusters which have `instanceType` configs for
+// `service` in lexical order
+func (l *Loader) ListClusters(service, instanceType string) ([]string, error) {
+	files, err := fs.Glob(l.FS, path.Join(service, instanceType+"-*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	clusters := make([]string, 0, len(files))
+	prefix := instanceType + "-"
+	for _, file := range files {
+		clusters = append(clusters, strings.TrimSuffix(strings.TrimPrefix(path.Base(file), prefix), ".yaml"))
+	}
+	sort.Strings(clusters)
+	return clusters, nil
+}
+
+// ListInstances returns instances of `instanceType` defined for `service`
+// in `cluster` in lexical order, templates are skipped
+func (l *Loader) ListInstances(service, instanceType, cluster string) ([]string, error) {
+	configs, err := l.readYaml(instanceConfigFile(service, instanceType, cluster))
+	if err != nil {
+		return nil, err
+	}
+	instances := make([]string, 0, len(configs))
+	for instance := range configs {
+		if !strings.HasPrefix(instance, "_") {
+			instances = append(instances, instance)
+		}
+	}
+	sort.Strings(instances)
+	return instances, nil
+}
+
+// ReadInstanceConfig returns config of `instance` merged on top of
+// `service.yaml` defaults
+func (l *Loader) ReadInstanceConfig(service, instanceType, cluster, instance string) (map[string]interface{}, error) {
+	if strings.HasPrefix(instance, "_") {
+		return nil, fmt.Errorf("%s is a template, not an instance", instance)
+	}
+	filename := instanceConfigFile(service, instanceType, cluster)
+	configs, err := l.readYaml(filename)
+	if err != nil {
+		return nil, err
+	}
+	instanceConfig, ok := configs[instance]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s.%s in %s", ErrInstanceNotFound, service, instance, filename)
+	}
+	if instanceConfig == nil {
+		instanceConfig = map[string]interface{}{}
+	}
+
+	defaults, err := l.ReadServiceConfig(service)
+	if err != nil {
+		return nil, err
+	}
+	merged, ok := normalize(configstore.DeepMerge(defaults, instanceConfig)).(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("config of %s.%s in %s is not a dictionary", service, instance, filename)
+	}
+	return merged, nil
+}
+
+// LoadInstanceConfig decodes config returned by `ReadInstanceConfig` into
+// `dst` using its JSON field tags
+func (l *Loader) LoadInstanceConfig(service, instanceType, cluster, instance string, dst interface{}) error {
+	config, err := l.ReadInstanceConfig(service, instanceType, cluster, instance)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, dst); err != nil {
+		return fmt.Errorf("Failed to decode %s.%s in %s: %v", service, instance, cluster, err)
+	}
+	return nil
+}
+
+// normalize converts dictionaries decoded from YAML to
+// map[string]interface{}, so that they can be encoded as JSON
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		res := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			res[fmt.Sprint(key)] = normalize(val)
+		}
+		return res
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			res[key] = normalize(val)
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, len(v))
+		for idx, val := range v {
+			res[idx] = normalize(val)
+		}
+		return res
+	}
+	return value
+}
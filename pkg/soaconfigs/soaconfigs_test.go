@@ -0,0 +1,111 @@
+package soaconfigs
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Yelp/paasta-tools-go/pkg/containerspec"
+	"github.com/Yelp/paasta-tools-go/pkg/volumes"
+	"github.com/stretchr/testify/assert"
+)
+
+var fakeSoaConfigs = fstest.MapFS{
+	"fluffy/service.yaml": {Data: []byte(`
+description: fluffy service
+cpus: 0.5
+env:
+  SERVICE_NAME: fluffy
+  LOG_LEVEL: info
+`)},
+	"fluffy/kubernetes-norcal.yaml": {Data: []byte(`
+_canary: &canary
+  deploy_group: canary
+  instances: 1
+  mem: 1024
+
+main:
+  instances: 3
+  cpus: 1
+  iam_role: arn:aws:iam::123456789012:role/fluffy
+  env:
+    LOG_LEVEL: debug
+  extra_volumes:
+    - {hostPath: /nail/srv, containerPath: /nail/srv, mode: RO}
+
+canary:
+  <<: *canary
+  registrations: [fluffy.main]
+`)},
+	"fluffy/kubernetes-pnw.yaml": {Data: []byte("main: {}\n")},
+	"fluffy/smartstack.yaml": {Data: []byte(`
+_template: &template
+  mode: http
+main:
+  <<: *template
+  proxy_port: 20001
+`)},
+}
+
+func TestLoader_ListInstances(test *testing.T) {
+	loader := &Loader{FS: fakeSoaConfigs}
+
+	clusters, err := loader.ListClusters("fluffy", KubernetesInstanceType)
+	assert.NoError(test, err)
+	assert.Equal(test, []string{"norcal", "pnw"}, clusters)
+
+	instances, err := loader.ListInstances("fluffy", KubernetesInstanceType, "norcal")
+	assert.NoError(test, err)
+	assert.Equal(test, []string{"canary", "main"}, instances)
+
+	instances, err = loader.ListInstances("fluffy", KubernetesInstanceType, "missing")
+	assert.NoError(test, err)
+	assert.Empty(test, instances)
+}
+
+func TestLoader_LoadKubernetesDeploymentConfig(test *testing.T) {
+	loader := &Loader{FS: fakeSoaConfigs}
+
+	config, err := loader.LoadKubernetesDeploymentConfig("fluffy", "norcal", "main")
+	assert.NoError(test, err)
+	assert.Equal(test, "norcal.main", config.DeployGroup)
+	assert.Equal(test, 3, *config.Instances)
+	assert.Equal(test, containerspec.KubeResourceQuantity("1"), *config.CPU)
+	assert.Nil(test, config.Memory)
+	assert.Equal(test, "arn:aws:iam::123456789012:role/fluffy", *config.IamRole)
+	assert.Equal(test, map[string]string{"SERVICE_NAME": "fluffy", "LOG_LEVEL": "debug"}, config.Env)
+	assert.Equal(
+		test,
+		[]volumes.Volume{{HostPath: "/nail/srv", ContainerPath: "/nail/srv", Mode: "RO"}},
+		config.ExtraVolumes,
+	)
+	assert.Equal(test, []string{"fluffy.main"}, config.GetRegistrations())
+
+	config, err = loader.LoadKubernetesDeploymentConfig("fluffy", "norcal", "canary")
+	assert.NoError(test, err)
+	assert.Equal(test, "canary", config.DeployGroup)
+	assert.Equal(test, 1, *config.Instances)
+	assert.Equal(test, containerspec.KubeResourceQuantity("0.5"), *config.CPU)
+	assert.Equal(test, containerspec.KubeResourceQuantity("1024"), *config.Memory)
+
+	config, err = loader.LoadKubernetesDeploymentConfig("fluffy", "pnw", "main")
+	assert.NoError(test, err)
+	assert.Equal(test, "pnw.main", config.DeployGroup)
+
+	_, err = loader.LoadKubernetesDeploymentConfig("fluffy", "norcal", "missing")
+	assert.True(test, errors.Is(err, ErrInstanceNotFound), "unexpected error: %v", err)
+	_, err = loader.LoadKubernetesDeploymentConfig("fluffy", "norcal", "_canary")
+	assert.Error(test, err)
+}
+
+func TestLoader_ReadSmartstack(test *testing.T) {
+	loader := &Loader{FS: fakeSoaConfigs}
+	namespaces, err := loader.ReadSmartstack("fluffy")
+	assert.NoError(test, err)
+	port := 20001
+	expected := map[string]SmartstackNamespace{"main": {Mode: "http", ProxyPort: &port}}
+	if !reflect.DeepEqual(expected, namespaces) {
+		test.Errorf("unexpected namespaces %+v", namespaces)
+	}
+}
@@ -0,0 +1,161 @@
+// Package systemconfig provides typed access to PaaSTA system config, which
+// is stored in `/etc/paasta` and shared by all services on a host.
+package systemconfig
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+
+	"github.com/Yelp/paasta-tools-go/pkg/configstore"
+)
+
+// DefaultDir is where system config is stored
+const DefaultDir = "/etc/paasta"
+
+// Documented system config keys
+const (
+	DockerRegistryKey        = "docker_registry"
+	ZipkinURLKey             = "paasta_zipkin_url"
+	VolumesKey               = "volumes"
+	HacheckSidecarVolumesKey = "hacheck_sidecar_volumes"
+	JwtServiceAuthKey        = "service_auth_token_settings"
+)
+
+// DefaultTokenExpirationSeconds is used when `expiration_seconds` is missing
+// from service authentication token settings
+const DefaultTokenExpirationSeconds = 3600
+
+// Hints maps system config keys to files which aren't named after them
+var Hints = map[string]string{
+	ZipkinURLKey:      "paasta",
+	JwtServiceAuthKey: "jwt_service_auth",
+}
+
+// Volume is a host path mounted into containers
+type Volume struct {
+	HostPath      string `json:"hostPath" mapstructure:"hostPath"`
+	ContainerPath string `json:"containerPath" mapstructure:"containerPath"`
+	Mode          string `json:"mode" mapstructure:"mode"`
+}
+
+// Validate checks that paths are absolute and mode is `RO` or `RW`
+func (v Volume) Validate() error {
+	if !path.IsAbs(v.HostPath) {
+		return fmt.Errorf("hostPath %q must be absolute", v.HostPath)
+	}
+	if !path.IsAbs(v.ContainerPath) {
+		return fmt.Errorf("containerPath %q must be absolute", v.ContainerPath)
+	}
+	if v.Mode != "RO" && v.Mode != "RW" {
+		return fmt.Errorf("mode of %s must be RO or RW, got %q", v.HostPath, v.Mode)
+	}
+	return nil
+}
+
+// JwtServiceAuthConfig holds settings of projected service account tokens
+// used for service authentication
+type JwtServiceAuthConfig struct {
+	Audience          string `json:"audience" mapstructure:"audience"`
+	ContainerPath     string `json:"container_path" mapstructure:"container_path"`
+	ExpirationSeconds int64  `json:"expiration_seconds" mapstructure:"expiration_seconds"`
+}
+
+// SystemPaastaConfig reads system config keys from a config store
+type SystemPaastaConfig struct {
+	store configstore.Reader
+}
+
+// New creates system config backed by `store`, which is never modified.
+// Stores created with `NewStore` have hints for system config keys, other
+// stores fall back to loading all files for keys stored in differently named
+// files.
+func New(store configstore.Reader) *SystemPaastaConfig {
+	return &SystemPaastaConfig{store: store}
+}
+
+// NewStore creates a config store reading system config from `dir`, with
+// hints for system config keys
+func NewStore(dir string) *configstore.Store {
+	hints := make(map[string]string, len(Hints))
+	for key, file := range Hints {
+		hints[key] = file
+	}
+	return configstore.NewStore(dir, hints)
+}
+
+// NewDefault creates system config backed by a store reading `DefaultDir`
+func NewDefault() *SystemPaastaConfig {
+	return New(NewStore(DefaultDir))
+}
+
+// Store returns the config store backing system config
+func (c *SystemPaastaConfig) Store() configstore.Reader {
+	return c.store
+}
+
+// DockerRegistry returns the registry service images are pulled from
+func (c *SystemPaastaConfig) DockerRegistry() (string, error) {
+	registry, err := configstore.Get[string](c.store, DockerRegistryKey)
+	if err != nil {
+		return "", err
+	}
+	if registry == "" {
+		return "", fmt.Errorf("%s must not be empty", DockerRegistryKey)
+	}
+	return registry, nil
+}
+
+// ZipkinURL returns the URL zipkin spans are reported to, or an empty string
+// when tracing isn't configured
+func (c *SystemPaastaConfig) ZipkinURL() (string, error) {
+	zipkinURL, err := configstore.GetOr(c.store, ZipkinURLKey, "")
+	if err != nil || zipkinURL == "" {
+		return "", err
+	}
+	if _, err := url.ParseRequestURI(zipkinURL); err != nil {
+		return "", fmt.Errorf("invalid %s: %v", ZipkinURLKey, err)
+	}
+	return zipkinURL, nil
+}
+
+func (c *SystemPaastaConfig) volumes(key string) ([]Volume, error) {
+	volumes, err := configstore.Get[[]Volume](c.store, key)
+	if err != nil {
+		return nil, err
+	}
+	for idx, volume := range volumes {
+		if err := volume.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid %s[%d]: %v", key, idx, err)
+		}
+	}
+	return volumes, nil
+}
+
+// Volumes returns volumes mounted into all service containers
+func (c *SystemPaastaConfig) Volumes() ([]Volume, error) {
+	return c.volumes(VolumesKey)
+}
+
+// HacheckSidecarVolumes returns volumes mounted into hacheck sidecars
+func (c *SystemPaastaConfig) HacheckSidecarVolumes() ([]Volume, error) {
+	return c.volumes(HacheckSidecarVolumesKey)
+}
+
+// JwtServiceAuth returns service authentication token settings, expiration
+// defaults to `DefaultTokenExpirationSeconds`
+func (c *SystemPaastaConfig) JwtServiceAuth() (JwtServiceAuthConfig, error) {
+	settings, err := configstore.Get[JwtServiceAuthConfig](c.store, JwtServiceAuthKey)
+	if err != nil {
+		return JwtServiceAuthConfig{}, err
+	}
+	if settings.Audience == "" || settings.ContainerPath == "" {
+		return JwtServiceAuthConfig{}, fmt.Errorf(
+			"Missing token settings in %s configuration", Hints[JwtServiceAuthKey],
+		)
+	}
+	if settings.ExpirationSeconds == 0 {
+		settings.ExpirationSeconds = DefaultTokenExpirationSeconds
+	}
+	return settings, nil
+}
@@ -0,0 +1,90 @@
+package systemconfig
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Yelp/paasta-tools-go/pkg/configstore"
+)
+
+func TestSystemPaastaConfig(test *testing.T) {
+	fsys := fstest.MapFS{
+		"docker_registry.json": {Data: []byte(`{"docker_registry": "docker.yelp.com"}`)},
+		"paasta.json":          {Data: []byte(`{"paasta_zipkin_url": "http://zipkin.yelp.com:9411/api/v2/spans"}`)},
+		"volumes.json": {Data: []byte(`{"volumes": [
+			{"hostPath": "/nail/etc", "containerPath": "/nail/etc", "mode": "RO"}
+		]}`)},
+		"hacheck_sidecar_volumes.json": {Data: []byte(`{"hacheck_sidecar_volumes": [
+			{"hostPath": "/var/run", "containerPath": "run", "mode": "RW"}
+		]}`)},
+		"jwt_service_auth.yaml": {Data: []byte(`
+service_auth_token_settings:
+  audience: foo.yelp.com
+  container_path: /var/secret/serviceaccount/foo
+`)},
+	}
+	config := New(configstore.NewStoreFromFS(fsys, ".", nil))
+
+	registry, err := config.DockerRegistry()
+	if err != nil || registry != "docker.yelp.com" {
+		test.Errorf("unexpected registry %q, err: %v", registry, err)
+	}
+
+	zipkinURL, err := config.ZipkinURL()
+	if err != nil || zipkinURL != "http://zipkin.yelp.com:9411/api/v2/spans" {
+		test.Errorf("unexpected zipkin url %q, err: %v", zipkinURL, err)
+	}
+
+	volumes, err := config.Volumes()
+	expected := []Volume{{HostPath: "/nail/etc", ContainerPath: "/nail/etc", Mode: "RO"}}
+	if err != nil || !reflect.DeepEqual(expected, volumes) {
+		test.Errorf("unexpected volumes %+v, err: %v", volumes, err)
+	}
+
+	if _, err := config.HacheckSidecarVolumes(); err == nil {
+		test.Errorf("expected error for relative containerPath")
+	}
+
+	settings, err := config.JwtServiceAuth()
+	expectedSettings := JwtServiceAuthConfig{
+		Audience:          "foo.yelp.com",
+		ContainerPath:     "/var/secret/serviceaccount/foo",
+		ExpirationSeconds: DefaultTokenExpirationSeconds,
+	}
+	if err != nil || settings != expectedSettings {
+		test.Errorf("unexpected jwt settings %+v, err: %v", settings, err)
+	}
+}
+
+func TestSystemPaastaConfigDefaults(test *testing.T) {
+	config := New(configstore.NewStoreFromMap(map[string]interface{}{
+		DockerRegistryKey: "",
+	}))
+
+	zipkinURL, err := config.ZipkinURL()
+	if err != nil || zipkinURL != "" {
+		test.Errorf("unexpected zipkin url %q, err: %v", zipkinURL, err)
+	}
+	if _, err := config.DockerRegistry(); err == nil {
+		test.Errorf("expected error for empty registry")
+	}
+	if _, err := config.Volumes(); !errors.Is(err, configstore.ErrKeyNotFound) {
+		test.Errorf("expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestNewDoesNotModifyStore(test *testing.T) {
+	store := configstore.NewStore(test.TempDir(), nil)
+	New(store)
+	if len(store.Hints) != 0 {
+		test.Errorf("unexpected hints %+v", store.Hints)
+	}
+
+	first, second := NewStore(test.TempDir()), NewStore(test.TempDir())
+	first.AddHint("foo", "bar")
+	if !reflect.DeepEqual(Hints, second.Hints) {
+		test.Errorf("hints shared between stores: %+v", second.Hints)
+	}
+}
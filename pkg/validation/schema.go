@@ -0,0 +1,143 @@
+package validation
+
+import (
+	"fmt"
+	"sort"
+)
+
+// fieldType is the expected type of a value in an instance config
+type fieldType string
+
+const (
+	typeString   fieldType = "string"
+	typeInteger  fieldType = "integer"
+	typeNumber   fieldType = "number"
+	typeBoolean  fieldType = "boolean"
+	typeArray    fieldType = "array"
+	typeObject   fieldType = "object"
+	typeQuantity fieldType = "quantity"
+)
+
+// kubernetesSchema lists known keys of kubernetes instance configs and
+// their types
+var kubernetesSchema = map[string]fieldType{
+	"annotations":                          typeObject,
+	"args":                                 typeArray,
+	"autoscaling":                          typeObject,
+	"bounce_health_params":                 typeObject,
+	"bounce_margin_factor":                 typeNumber,
+	"bounce_method":                        typeString,
+	"bounce_priority":                      typeInteger,
+	"cap_add":                              typeArray,
+	"cmd":                                  typeString,
+	"container_port":                       typeInteger,
+	"cpu_burst_add":                        typeNumber,
+	"cpus":                                 typeQuantity,
+	"cpus_limit":                           typeQuantity,
+	"cron_expression":                      typeString,
+	"deploy_blacklist":                     typeArray,
+	"deploy_group":                         typeString,
+	"deploy_whitelist":                     typeArray,
+	"desired_state":                        typeString,
+	"disk":                                 typeQuantity,
+	"disk_limit":                           typeQuantity,
+	"env":                                  typeObject,
+	"extra_constraints":                    typeArray,
+	"extra_volumes":                        typeArray,
+	"fs_group":                             typeInteger,
+	"healthcheck_cmd":                      typeString,
+	"healthcheck_grace_period_seconds":     typeNumber,
+	"healthcheck_interval_seconds":         typeNumber,
+	"healthcheck_max_consecutive_failures": typeInteger,
+	"healthcheck_mode":                     typeString,
+	"healthcheck_timeout_seconds":          typeNumber,
+	"healthcheck_uri":                      typeString,
+	"iam_role":                             typeString,
+	"iam_role_provider":                    typeString,
+	"instances":                            typeInteger,
+	"lifecycle":                            typeObject,
+	"max_instances":                        typeInteger,
+	"mem":                                  typeQuantity,
+	"mem_limit":                            typeQuantity,
+	"min_instances":                        typeInteger,
+	"monitoring":                           typeObject,
+	"net":                                  typeString,
+	"node_selectors":                       typeObject,
+	"pod_management_policy":                typeString,
+	"prometheus_path":                      typeString,
+	"prometheus_port":                      typeInteger,
+	"prometheus_shard":                     typeString,
+	"registrations":                        typeArray,
+	"replication_threshold":                typeInteger,
+	"routable_ip":                          typeBoolean,
+	"secret_volumes":                       typeArray,
+	"sidecar_resource_requirements":        typeObject,
+	"termination_grace_period_seconds":     typeInteger,
+	"topology_spread_constraints":          typeArray,
+	"ulimit":                               typeObject,
+	"uses_bulkdata":                        typeBoolean,
+	"weight":                               typeInteger,
+}
+
+// matches tells whether a value decoded from YAML has type `t`
+func (t fieldType) matches(value interface{}) bool {
+	switch t {
+	case typeString:
+		_, ok := value.(string)
+		return ok
+	case typeInteger:
+		_, ok := value.(int)
+		return ok
+	case typeNumber:
+		switch value.(type) {
+		case int, float64:
+			return true
+		}
+	case typeBoolean:
+		_, ok := value.(bool)
+		return ok
+	case typeArray:
+		_, ok := value.([]interface{})
+		return ok
+	case typeObject:
+		_, ok := value.(map[string]interface{})
+		return ok
+	case typeQuantity:
+		switch value.(type) {
+		case int, float64, string:
+			return true
+		}
+	}
+	return false
+}
+
+// fieldError is a problem with a key of an instance config
+type fieldError struct {
+	key     string
+	message string
+}
+
+// checkSchema reports keys of `config` which have unexpected types, unknown
+// keys are reported with `strict` only. Errors are in lexical order of keys.
+func checkSchema(schema map[string]fieldType, config map[string]interface{}, strict bool) []fieldError {
+	keys := make([]string, 0, len(config))
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	errors := []fieldError{}
+	for _, key := range keys {
+		expected, ok := schema[key]
+		if !ok {
+			if strict {
+				errors = append(errors, fieldError{key, "unknown key"})
+			}
+			continue
+		}
+		if !expected.matches(config[key]) {
+			errors = append(errors, fieldError{key, fmt.Sprintf("expected %s, got %#v", expected, config[key])})
+		}
+	}
+	return errors
+}
@@ -0,0 +1,230 @@
+// Package validation checks soa-configs of a service, e.g. before they are
+// committed, and reports problems with file and line numbers.
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/Yelp/paasta-tools-go/pkg/configstore"
+	"github.com/Yelp/paasta-tools-go/pkg/deployments"
+	"github.com/Yelp/paasta-tools-go/pkg/soaconfigs"
+	"gopkg.in/yaml.v3"
+)
+
+var iamRoleArnRegex = regexp.MustCompile(`^arn:aws:iam::[0-9]{12}:role/[\w+=,.@/-]+$`)
+
+// Error is a problem found in a config file, `Line` is 0 when it doesn't
+// apply to a particular line
+type Error struct {
+	File    string
+	Line    int
+	Message string
+}
+
+func (e Error) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %s", e.File, e.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Message)
+}
+
+// Validator checks service configs read with `Loader`
+type Validator struct {
+	Loader *soaconfigs.Loader
+	// Strict reports keys missing from the schema
+	Strict bool
+	// SkipDeployments disables checks against `deployments.json`
+	SkipDeployments bool
+}
+
+// NewValidator creates a validator reading soa-configs from `root`
+func NewValidator(root string) *Validator {
+	return &Validator{Loader: soaconfigs.NewLoader(root)}
+}
+
+// instanceFile is a parsed `<instance type>-<cluster>.yaml`
+type instanceFile struct {
+	name    string
+	cluster string
+	root    *yaml.Node
+	configs map[string]interface{}
+}
+
+// line returns the line of the key at `keys` path in the file, or the line
+// of the closest parent found. Keys merged from templates are looked up in
+// the templates.
+func (f *instanceFile) line(keys ...string) int {
+	node := f.root
+	if node != nil && node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	line := 0
+	for _, key := range keys {
+		keyNode, valueNode := lookup(node, key)
+		if keyNode == nil {
+			break
+		}
+		line = keyNode.Line
+		node = valueNode
+	}
+	return line
+}
+
+// lookup returns key and value nodes of `key` in a mapping node, following
+// aliases and merge keys
+func lookup(node *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	for node != nil && node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for idx := 0; idx+1 < len(node.Content); idx += 2 {
+		if node.Content[idx].Value == key {
+			return node.Content[idx], node.Content[idx+1]
+		}
+	}
+	for idx := 0; idx+1 < len(node.Content); idx += 2 {
+		if node.Content[idx].Tag == "!!merge" {
+			if keyNode, valueNode := lookup(node.Content[idx+1], key); keyNode != nil {
+				return keyNode, valueNode
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (v *Validator) readInstanceFile(service, name, cluster string) (*instanceFile, error) {
+	data, err := fs.ReadFile(v.Loader.FS, path.Join(service, name))
+	if err != nil {
+		return nil, err
+	}
+	file := &instanceFile{name: path.Join(service, name), cluster: cluster, root: &yaml.Node{}}
+	if err := yaml.Unmarshal(data, file.root); err != nil {
+		return nil, err
+	}
+	file.configs = map[string]interface{}{}
+	if err := file.root.Decode(&file.configs); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// ValidateService checks all kubernetes instances of `service`, problems
+// with configs are returned as errors in the list, failures to read them as
+// the error
+func (v *Validator) ValidateService(service string) ([]Error, error) {
+	clusters, err := v.Loader.ListClusters(service, soaconfigs.KubernetesInstanceType)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list clusters of %s: %v", service, err)
+	}
+
+	res := []Error{}
+	var deploymentsConfig *deployments.V2DeploymentsConfig
+	if !v.SkipDeployments {
+		store := configstore.NewStoreFromFS(v.Loader.FS, service, map[string]string{"v2": "deployments"})
+		config, err := configstore.Get[deployments.V2DeploymentsConfig](store, "v2")
+		if errors.Is(err, configstore.ErrKeyNotFound) {
+			res = append(res, Error{File: path.Join(service, "deployments.json"), Message: "v2 deployments not found"})
+		} else if err != nil {
+			return nil, fmt.Errorf("Failed to read deployments of %s: %v", service, err)
+		} else {
+			deploymentsConfig = &config
+		}
+	}
+
+	for _, cluster := range clusters {
+		name := fmt.Sprintf("%s-%s.yaml", soaconfigs.KubernetesInstanceType, cluster)
+		file, err := v.readInstanceFile(service, name, cluster)
+		if err != nil {
+			res = append(res, Error{File: path.Join(service, name), Message: err.Error()})
+			continue
+		}
+		instances, err := v.Loader.ListInstances(service, soaconfigs.KubernetesInstanceType, cluster)
+		if err != nil {
+			return nil, err
+		}
+		for _, instance := range instances {
+			res = append(res, v.validateInstance(service, instance, file, deploymentsConfig)...)
+		}
+	}
+	return res, nil
+}
+
+func (v *Validator) validateInstance(
+	service, instance string, file *instanceFile, deploymentsConfig *deployments.V2DeploymentsConfig,
+) []Error {
+	res := []Error{}
+	errorAt := func(message string, keys ...string) {
+		keys = append([]string{instance}, keys...)
+		if keys[len(keys)-1] == "" {
+			keys = keys[:len(keys)-1]
+		}
+		res = append(res, Error{
+			File:    file.name,
+			Line:    file.line(keys...),
+			Message: fmt.Sprintf("%s: %s", strings.Join(keys, "."), message),
+		})
+	}
+
+	raw, ok := file.configs[instance].(map[string]interface{})
+	if !ok && file.configs[instance] != nil {
+		errorAt("instance config must be a dictionary")
+		return res
+	}
+	for _, fieldErr := range checkSchema(kubernetesSchema, raw, v.Strict) {
+		errorAt(fieldErr.message, fieldErr.key)
+	}
+	if len(res) > 0 {
+		// typed checks below can't decode configs with unexpected types
+		return res
+	}
+
+	config, err := v.Loader.LoadKubernetesDeploymentConfig(service, file.cluster, instance)
+	if err != nil {
+		errorAt(err.Error())
+		return res
+	}
+
+	if _, err := config.GetContainerResources(); err != nil {
+		errorAt(err.Error(), resourceKey(raw))
+	}
+
+	for idx, volume := range config.ExtraVolumes {
+		if volume.Mode != "RO" && volume.Mode != "RW" {
+			errorAt(fmt.Sprintf("mode of volume %d must be RO or RW, got %q", idx, volume.Mode), "extra_volumes")
+		}
+	}
+
+	if config.IamRole != nil && *config.IamRole != "" && !iamRoleArnRegex.MatchString(*config.IamRole) {
+		errorAt(fmt.Sprintf("%q is not a valid IAM role ARN", *config.IamRole), "iam_role")
+	}
+
+	if deploymentsConfig != nil {
+		deployGroup := config.GetDeployGroup()
+		if _, ok := deploymentsConfig.Deployments[deployGroup]; !ok {
+			errorAt(fmt.Sprintf("deploy group %s not found in deployments.json", deployGroup), "deploy_group")
+		}
+		controlGroup := fmt.Sprintf("%s:%s.%s", service, file.cluster, instance)
+		if _, ok := deploymentsConfig.Controls[controlGroup]; !ok {
+			errorAt(fmt.Sprintf("control group %s not found in deployments.json", controlGroup))
+		}
+	}
+	return res
+}
+
+// resourceKey returns the first resource key set in `config` to point
+// resource errors at
+func resourceKey(config map[string]interface{}) string {
+	for _, key := range []string{"cpus", "cpus_limit", "mem", "mem_limit", "disk", "disk_limit"} {
+		if _, ok := config[key]; ok {
+			return key
+		}
+	}
+	return ""
+}
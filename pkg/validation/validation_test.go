@@ -0,0 +1,81 @@
+package validation
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/Yelp/paasta-tools-go/pkg/soaconfigs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidator_ValidateService(test *testing.T) {
+	fsys := fstest.MapFS{
+		"fluffy/service.yaml": {Data: []byte("description: fluffy\ncpus: 0.5\n")},
+		"fluffy/kubernetes-norcal.yaml": {Data: []byte(`_template: &template
+  mem: 1024
+  iam_role: not-an-arn
+
+main:
+  instances: 3
+  iam_role: arn:aws:iam::123456789012:role/fluffy
+  extra_volumes:
+    - {hostPath: /nail/srv, containerPath: /nail/srv, mode: RO}
+
+canary:
+  <<: *template
+  instances: two
+  deploy_group: canary
+
+batch:
+  cpus: 2
+  cpus_limit: 1
+  extra_volumes:
+    - {hostPath: /tmp, containerPath: /tmp, mode: rw}
+  unknown_key: true
+`)},
+		"fluffy/deployments.json": {Data: []byte(`{"v2": {
+			"deployments": {"norcal.main": {"docker_image": "fluffy:1", "git_sha": "abc"}},
+			"controls": {
+				"fluffy:norcal.main": {"desired_state": "start", "force_bounce": null},
+				"fluffy:norcal.batch": {"desired_state": "start", "force_bounce": null}
+			}
+		}}`)},
+	}
+	validator := &Validator{Loader: &soaconfigs.Loader{FS: fsys}}
+
+	errors, err := validator.ValidateService("fluffy")
+	assert.NoError(test, err)
+	assert.Equal(test, []Error{
+		{File: "fluffy/kubernetes-norcal.yaml", Line: 17, Message: "batch.cpus: cpu limit '1' must not be smaller than cpu '2'"},
+		{File: "fluffy/kubernetes-norcal.yaml", Line: 19, Message: `batch.extra_volumes: mode of volume 0 must be RO or RW, got "rw"`},
+		{File: "fluffy/kubernetes-norcal.yaml", Line: 16, Message: "batch.deploy_group: deploy group norcal.batch not found in deployments.json"},
+		{File: "fluffy/kubernetes-norcal.yaml", Line: 13, Message: "canary.instances: expected integer, got \"two\""},
+	}, errors)
+
+	validator.Strict = true
+	fsys["fluffy/kubernetes-norcal.yaml"] = &fstest.MapFile{Data: []byte(`canary:
+  deploy_group: canary
+  iam_role: not-an-arn
+  unknown_key: true
+`)}
+	errors, err = validator.ValidateService("fluffy")
+	assert.NoError(test, err)
+	assert.Equal(test, []Error{
+		{File: "fluffy/kubernetes-norcal.yaml", Line: 4, Message: "canary.unknown_key: unknown key"},
+	}, errors)
+
+	validator.Strict = false
+	errors, err = validator.ValidateService("fluffy")
+	assert.NoError(test, err)
+	assert.Equal(test, []Error{
+		{File: "fluffy/kubernetes-norcal.yaml", Line: 3, Message: `canary.iam_role: "not-an-arn" is not a valid IAM role ARN`},
+		{File: "fluffy/kubernetes-norcal.yaml", Line: 2, Message: "canary.deploy_group: deploy group canary not found in deployments.json"},
+		{File: "fluffy/kubernetes-norcal.yaml", Line: 1, Message: "canary: control group fluffy:norcal.canary not found in deployments.json"},
+	}, errors)
+
+	delete(fsys, "fluffy/deployments.json")
+	errors, err = validator.ValidateService("fluffy")
+	assert.NoError(test, err)
+	assert.Equal(test, Error{File: "fluffy/deployments.json", Message: "v2 deployments not found"}, errors[0])
+	assert.Equal(test, "fluffy/deployments.json: v2 deployments not found", errors[0].Error())
+}
@@ -7,27 +7,23 @@ import (
 	"time"
 
 	"github.com/Yelp/paasta-tools-go/pkg/configstore"
+	"github.com/Yelp/paasta-tools-go/pkg/systemconfig"
 	"gopkg.in/yaml.v2"
 	corev1 "k8s.io/api/core/v1"
 )
 
 const (
 	authenticatingServicesConfigPath    = "/nail/etc/services/authenticating.yaml"
-	jwtServiceAuthConfigName            = "jwt_service_auth"
-	jwtServiceAuthConfigKey             = "service_auth_token_settings"
+	jwtServiceAuthConfigKey             = systemconfig.JwtServiceAuthKey
 	authenticatingServicesCacheDuration = 10 * time.Minute
-	defaultTokenExpirationSeconds       = 3600
+	defaultTokenExpirationSeconds       = systemconfig.DefaultTokenExpirationSeconds
 )
 
 type authenticatingServicesConfig struct {
 	Services []string `yaml:"services"`
 }
 
-type jwtServiceAuthTokenSettings struct {
-	Audience          string `json:"audience" mapstructure:"audience"`
-	ContainerPath     string `json:"container_path" mapstructure:"container_path"`
-	ExpirationSeconds int64  `json:"expiration_seconds" mapstructure:"expiration_seconds"`
-}
+type jwtServiceAuthTokenSettings = systemconfig.JwtServiceAuthConfig
 
 var authenticatingServicesCache map[string]bool
 var authenticatingServicesLastLoaded time.Time
@@ -103,16 +99,12 @@ func GetProjectedServiceAccountVolume(audience string, path string, expirationSe
 }
 
 func GetServiceAuthenticationTokenVolume(configStore *configstore.Store) (corev1.VolumeMount, corev1.Volume, error) {
-	var tokenSettings jwtServiceAuthTokenSettings
-	configStore.AddHint(jwtServiceAuthConfigKey, jwtServiceAuthConfigName)
-	if ok, err := configStore.Load(jwtServiceAuthConfigKey, &tokenSettings); !ok || err != nil {
-		if err == nil {
-			err = fmt.Errorf("%s configuration not found", jwtServiceAuthConfigName)
-		}
-		return corev1.VolumeMount{}, corev1.Volume{}, err
+	if _, ok := configStore.Hints[jwtServiceAuthConfigKey]; !ok {
+		configStore.AddHint(jwtServiceAuthConfigKey, systemconfig.Hints[jwtServiceAuthConfigKey])
 	}
-	if tokenSettings.Audience == "" || tokenSettings.ContainerPath == "" {
-		return corev1.VolumeMount{}, corev1.Volume{}, fmt.Errorf("Missing token settings in %s configuration", jwtServiceAuthConfigName)
+	tokenSettings, err := systemconfig.New(configStore).JwtServiceAuth()
+	if err != nil {
+		return corev1.VolumeMount{}, corev1.Volume{}, err
 	}
 	volume, volumeMount := GetProjectedServiceAccountVolume(tokenSettings.Audience, tokenSettings.ContainerPath, tokenSettings.ExpirationSeconds)
 	return volume, volumeMount, nil
@@ -2,6 +2,7 @@ package volumes
 
 import (
 	"os"
+	"path"
 	"reflect"
 	"sync"
 	"testing"
@@ -52,6 +53,26 @@ func TestGetServiceAuthenticationTokenVolume(t *testing.T) {
 	}
 }
 
+func TestGetServiceAuthenticationTokenVolumeWithoutHints(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"jwt_service_auth.json": `{"service_auth_token_settings": {"audience": "foo.yelp.com", "container_path": "/var/secret/serviceaccount/foo"}}`,
+		"README":                "not a config file",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(path.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	outputMount, _, err := GetServiceAuthenticationTokenVolume(configstore.NewStore(dir, nil))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if outputMount.MountPath != "/var/secret/serviceaccount/foo" {
+		t.Errorf("Wrong SA volume mount path: %s", outputMount.MountPath)
+	}
+}
+
 func TestFormatServiceAccountVolumeName(t *testing.T) {
 	testCases := []string{
 		"foobar",
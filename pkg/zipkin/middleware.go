@@ -0,0 +1,27 @@
+package zipkin
+
+import (
+	"fmt"
+	"net/http"
+
+	zipkinhttp "github.com/openzipkin/zipkin-go/middleware/http"
+)
+
+// NewTransport wraps rt (or http.DefaultTransport when rt is nil) with a
+// round tripper creating client spans for outgoing requests. Spans are
+// children of the span stored in the request context and B3 headers are
+// injected into the request.
+func NewTransport(rt http.RoundTripper) (http.RoundTripper, error) {
+	transport, err := zipkinhttp.NewTransport(Tracer(), zipkinhttp.RoundTripper(rt))
+	if err != nil {
+		return nil, fmt.Errorf("initializing transport: %v", err)
+	}
+	return transport, nil
+}
+
+// Middleware wraps h with a handler creating server spans for incoming
+// requests, joining traces propagated via B3 headers. The span is available
+// to h via `SpanFromContext(r.Context())`.
+func Middleware(h http.Handler) http.Handler {
+	return zipkinhttp.NewServerMiddleware(Tracer())(h)
+}
@@ -0,0 +1,60 @@
+package zipkin
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter"
+)
+
+var defaultTracer atomic.Pointer[zipkin.Tracer]
+
+func init() {
+	tr, _ := zipkin.NewTracer(reporter.NewNoopReporter())
+	defaultTracer.Store(tr)
+}
+
+// SetTracer replaces the tracer used by package level helpers, `InitZipkin`
+// calls it with the tracer it creates
+func SetTracer(tracer *zipkin.Tracer) {
+	if tracer != nil {
+		defaultTracer.Store(tracer)
+	}
+}
+
+// Tracer returns the tracer used by package level helpers, it is a noop
+// tracer until `InitZipkin` or `SetTracer` is called
+func Tracer() *zipkin.Tracer {
+	return defaultTracer.Load()
+}
+
+// ContextWithSpan returns a copy of ctx carrying span
+func ContextWithSpan(ctx context.Context, span zipkin.Span) context.Context {
+	return zipkin.NewContext(ctx, span)
+}
+
+// SpanFromContext returns the span stored in ctx or nil
+func SpanFromContext(ctx context.Context) zipkin.Span {
+	return zipkin.SpanFromContext(ctx)
+}
+
+// StartSpanFromContext starts a new span named `name`, which is a child of the
+// span stored in ctx if there is one, and returns it together with a context
+// carrying the new span
+func StartSpanFromContext(ctx context.Context, name string, options ...zipkin.SpanOption) (zipkin.Span, context.Context) {
+	return Tracer().StartSpanFromContext(ctx, name, options...)
+}
+
+// Trace runs fn inside a new span named `name`. The span is tagged with the
+// error returned by fn, if any, and finished before Trace returns.
+func Trace(ctx context.Context, name string, fn func(context.Context) error) error {
+	span, ctx := StartSpanFromContext(ctx, name)
+	defer span.Finish()
+
+	err := fn(ctx)
+	if err != nil {
+		zipkin.TagError.Set(span, err.Error())
+	}
+	return err
+}
@@ -0,0 +1,99 @@
+package zipkin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+	"github.com/stretchr/testify/assert"
+)
+
+func withRecordingTracer(t *testing.T) *recorder.ReporterRecorder {
+	rec := recorder.NewReporter()
+	tr, err := zipkin.NewTracer(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := Tracer()
+	SetTracer(tr)
+	t.Cleanup(func() { SetTracer(old) })
+	return rec
+}
+
+func TestTrace(t *testing.T) {
+	rec := withRecordingTracer(t)
+
+	err := Trace(context.Background(), "parent", func(ctx context.Context) error {
+		assert.NotNil(t, SpanFromContext(ctx))
+		return Trace(ctx, "child", func(context.Context) error {
+			return fmt.Errorf("boom")
+		})
+	})
+	assert.EqualError(t, err, "boom")
+
+	spans := rec.Flush()
+	if !assert.Len(t, spans, 2) {
+		return
+	}
+	child, parent := spans[0], spans[1]
+	assert.Equal(t, "child", child.Name)
+	assert.Equal(t, "parent", parent.Name)
+	assert.Equal(t, parent.TraceID, child.TraceID)
+	assert.Equal(t, parent.ID, *child.ParentID)
+	assert.Equal(t, "boom", child.Tags["error"])
+	assert.Equal(t, "boom", parent.Tags["error"])
+}
+
+func TestContextWithSpan(t *testing.T) {
+	withRecordingTracer(t)
+
+	assert.Nil(t, SpanFromContext(context.Background()))
+	span := Tracer().StartSpan("span")
+	ctx := ContextWithSpan(context.Background(), span)
+	assert.Equal(t, span, SpanFromContext(ctx))
+
+	child, _ := StartSpanFromContext(ctx, "child")
+	assert.Equal(t, span.Context().ID, *child.Context().ParentID)
+}
+
+func TestTransportAndMiddleware(t *testing.T) {
+	rec := withRecordingTracer(t)
+
+	var serverSpan zipkin.Span
+	server := httptest.NewServer(Middleware(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			serverSpan = SpanFromContext(r.Context())
+		},
+	)))
+	defer server.Close()
+
+	transport, err := NewTransport(nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	client := &http.Client{Transport: transport}
+
+	err = Trace(context.Background(), "request", func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	})
+	assert.NoError(t, err)
+	assert.NotNil(t, serverSpan)
+
+	spans := rec.Flush()
+	assert.Len(t, spans, 3)
+	for _, span := range spans {
+		assert.Equal(t, spans[0].TraceID, span.TraceID)
+	}
+}
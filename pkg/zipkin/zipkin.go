@@ -30,7 +30,8 @@ func init() {
 	zipkinInitializers["noop"] = &noopInitializer{}
 }
 
-// InitZipkin returns the reporter and tracer for zipkinURL
+// InitZipkin returns the reporter and tracer for zipkinURL, the tracer is also
+// used by package level helpers such as `Trace`
 func InitZipkin(zipkinURL string) (reporter.Reporter, *zipkin.Tracer, error) {
 	var initializer string
 	var errors []string
@@ -57,6 +58,7 @@ func InitZipkin(zipkinURL string) (reporter.Reporter, *zipkin.Tracer, error) {
 	if err != nil {
 		errors = append(errors, fmt.Sprintf("initializing %T: %v", initializerF, err))
 	}
+	SetTracer(tr)
 	if len(errors) > 0 {
 		err = fmt.Errorf("%s", strings.Join(errors, ", "))
 	}